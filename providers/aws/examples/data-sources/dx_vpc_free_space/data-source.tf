@@ -0,0 +1,8 @@
+data "dx_vpc_free_space" "this" {
+  vpc_id         = "vpc-0123456789abcdef0"
+  prefix_lengths = [24, 27]
+}
+
+output "free_24_blocks" {
+  value = data.dx_vpc_free_space.this.available_blocks["24"]
+}
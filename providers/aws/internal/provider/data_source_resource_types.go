@@ -0,0 +1,85 @@
+// Implementation of the data source listing the resource types supported by the naming function
+package provider
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSource = &resourceTypesDataSource{}
+
+func NewResourceTypesDataSource() datasource.DataSource {
+	return &resourceTypesDataSource{}
+}
+
+// Data source definition
+type resourceTypesDataSource struct {
+}
+
+// Data source model
+type resourceTypesDataSourceModel struct {
+	ResourceTypes []resourceTypeModel `tfsdk:"resource_types"`
+}
+
+type resourceTypeModel struct {
+	ResourceType types.String `tfsdk:"resource_type"`
+	Abbreviation types.String `tfsdk:"abbreviation"`
+	Category     types.String `tfsdk:"category"`
+}
+
+func (d *resourceTypesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_resource_types"
+}
+
+func (d *resourceTypesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the resource types supported by the resource_name function with their abbreviation and category.",
+
+		Attributes: map[string]schema.Attribute{
+			"resource_types": schema.ListNestedAttribute{
+				Description: "The supported resource types, sorted by resource type.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"resource_type": schema.StringAttribute{
+							Description: "The resource type, as accepted by resource_name.",
+							Computed:    true,
+						},
+						"abbreviation": schema.StringAttribute{
+							Description: "The abbreviation used in the generated names.",
+							Computed:    true,
+						},
+						"category": schema.StringAttribute{
+							Description: "The category of the resource type (e.g. Compute, Storage, Networking).",
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *resourceTypesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data resourceTypesDataSourceModel
+
+	for category, resourceTypes := range getResourceAbbreviationsByCategory() {
+		for resourceType, abbreviation := range resourceTypes {
+			data.ResourceTypes = append(data.ResourceTypes, resourceTypeModel{
+				ResourceType: types.StringValue(resourceType),
+				Abbreviation: types.StringValue(abbreviation),
+				Category:     types.StringValue(category),
+			})
+		}
+	}
+
+	sort.Slice(data.ResourceTypes, func(i, j int) bool {
+		return data.ResourceTypes[i].ResourceType.ValueString() < data.ResourceTypes[j].ResourceType.ValueString()
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,40 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+)
+
+func TestResourceTypesDataSource(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+data "dx_resource_types" "all" {}
+
+output "count" {
+  value = length(data.dx_resource_types.all.resource_types)
+}
+
+output "s3_bucket" {
+  value = one([for t in data.dx_resource_types.all.resource_types : t if t.resource_type == "s3_bucket"])
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("count", knownvalue.Int64Exact(int64(len(getResourceAbbreviations())))),
+					statecheck.ExpectKnownOutputValue("s3_bucket", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"resource_type": knownvalue.StringExact("s3_bucket"),
+						"abbreviation":  knownvalue.StringExact("s3"),
+						"category":      knownvalue.StringExact("Storage"),
+					})),
+				},
+			},
+		},
+	})
+}
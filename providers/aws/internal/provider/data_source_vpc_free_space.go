@@ -0,0 +1,239 @@
+// Implementation of the data source reporting the free address space of an AWS VPC
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/apparentlymart/go-cidr/cidr"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSource = &vpcFreeSpaceDataSource{}
+var _ datasource.DataSourceWithConfigure = &vpcFreeSpaceDataSource{}
+
+func NewVpcFreeSpaceDataSource() datasource.DataSource {
+	return &vpcFreeSpaceDataSource{}
+}
+
+// Data source definition
+type vpcFreeSpaceDataSource struct {
+	// ec2Client is the EC2 client configured in the provider
+	ec2Client ec2API
+}
+
+// Data source model
+type vpcFreeSpaceDataSourceModel struct {
+	VpcID                        types.String `tfsdk:"vpc_id"`
+	PrefixLengths                types.Set    `tfsdk:"prefix_lengths"`
+	FreeCidrs                    types.List   `tfsdk:"free_cidrs"`
+	LargestAvailablePrefixLength types.Int64  `tfsdk:"largest_available_prefix_length"`
+	AvailableBlocks              types.Map    `tfsdk:"available_blocks"`
+}
+
+func (d *vpcFreeSpaceDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_vpc_free_space"
+}
+
+func (d *vpcFreeSpaceDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reports the address space of an AWS VPC not used by its subnets, without reserving anything.",
+
+		Attributes: map[string]schema.Attribute{
+			"vpc_id": schema.StringAttribute{
+				Description: "The AWS VPC ID.",
+				Required:    true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`^vpc-[a-zA-Z0-9]+$`),
+						"must be a valid AWS VPC ID in the format vpc-xxxxxxxxx",
+					),
+				},
+			},
+			"prefix_lengths": schema.SetAttribute{
+				Description: "The IPv4 prefix lengths to count the free blocks of in available_blocks (e.g. [24, 27]).",
+				Optional:    true,
+				ElementType: types.Int64Type,
+				Validators: []validator.Set{
+					setvalidator.ValueInt64sAre(int64validator.Between(1, 28)), // AWS subnet limits
+				},
+			},
+			"free_cidrs": schema.ListAttribute{
+				Description: "The free ranges of the VPC CIDR blocks, as the fewest CIDR blocks covering them, in VPC CIDR block order.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"largest_available_prefix_length": schema.Int64Attribute{
+				Description: "The prefix length of the largest free IPv4 block, null when the IPv4 CIDR blocks are full.",
+				Computed:    true,
+			},
+			"available_blocks": schema.MapAttribute{
+				Description: "The number of free IPv4 blocks of each of the prefix_lengths, keyed by prefix length.",
+				Computed:    true,
+				ElementType: types.Int64Type,
+			},
+		},
+	}
+}
+
+// Configure reads the EC2 client configured in the provider
+func (d *vpcFreeSpaceDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.ec2Client = providerData.ec2Client
+}
+
+func (d *vpcFreeSpaceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data vpcFreeSpaceDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var prefixLengths []int64
+	if !data.PrefixLengths.IsNull() {
+		resp.Diagnostics.Append(data.PrefixLengths.ElementsAs(ctx, &prefixLengths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	vpcID := data.VpcID.ValueString()
+	layout, err := getVpcLayout(ctx, d.ec2Client, vpcID)
+	if errors.Is(err, errVpcNotFound) {
+		resp.Diagnostics.AddError(
+			"VPC Not Found",
+			fmt.Sprintf("VPC with ID %s not found", vpcID),
+		)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(awsAPIErrorDiagnostic(err))
+		return
+	}
+
+	vpcCIDRs := append(append([]string{}, layout.cidrBlocks...), layout.ipv6CidrBlocks...)
+	free := freeCidrBlocks(vpcCIDRs, layout.subnetCidrBlocks())
+
+	freeCidrs := make([]string, 0, len(free))
+	for _, block := range free {
+		freeCidrs = append(freeCidrs, block.String())
+	}
+	var diags diag.Diagnostics
+	data.FreeCidrs, diags = types.ListValueFrom(ctx, types.StringType, freeCidrs)
+	resp.Diagnostics.Append(diags...)
+
+	data.LargestAvailablePrefixLength = types.Int64Null()
+	if prefixLen, ok := largestFreePrefixLength(free); ok {
+		data.LargestAvailablePrefixLength = types.Int64Value(int64(prefixLen))
+	}
+
+	availableBlocks := make(map[string]int64, len(prefixLengths))
+	for _, prefixLen := range prefixLengths {
+		availableBlocks[strconv.FormatInt(prefixLen, 10)] = freeBlockCount(free, int(prefixLen))
+	}
+	data.AvailableBlocks, diags = types.MapValueFrom(ctx, types.Int64Type, availableBlocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// freeCidrBlocks returns the parts of the VPC CIDR blocks not overlapping the used blocks,
+// as the fewest aligned CIDR blocks covering them
+func freeCidrBlocks(vpcCIDRs, usedCIDRs []string) []*net.IPNet {
+	var used []*net.IPNet
+	for _, usedCIDR := range usedCIDRs {
+		if _, block, err := net.ParseCIDR(usedCIDR); err == nil {
+			used = append(used, block)
+		}
+	}
+
+	var free []*net.IPNet
+	for _, vpcCIDR := range vpcCIDRs {
+		if _, vpcNet, err := net.ParseCIDR(vpcCIDR); err == nil {
+			free = append(free, freeBlocksIn(vpcNet, used)...)
+		}
+	}
+	return free
+}
+
+// freeBlocksIn splits a block in halves until each half is either free or fully used
+func freeBlocksIn(block *net.IPNet, used []*net.IPNet) []*net.IPNet {
+	overlapping := false
+	for _, usedNet := range used {
+		if getNetworkPrefixLength(usedNet) <= getNetworkPrefixLength(block) && usedNet.Contains(block.IP) {
+			return nil
+		}
+		if block.Contains(usedNet.IP) {
+			overlapping = true
+		}
+	}
+	if !overlapping {
+		return []*net.IPNet{block}
+	}
+
+	lower, err := cidr.Subnet(block, 1, 0)
+	if err != nil {
+		return nil
+	}
+	upper, err := cidr.Subnet(block, 1, 1)
+	if err != nil {
+		return nil
+	}
+	return append(freeBlocksIn(lower, used), freeBlocksIn(upper, used)...)
+}
+
+// largestFreePrefixLength returns the prefix length of the largest free IPv4 block
+func largestFreePrefixLength(free []*net.IPNet) (int, bool) {
+	largest, found := 0, false
+	for _, block := range free {
+		if block.IP.To4() == nil {
+			continue
+		}
+		if prefixLen := getNetworkPrefixLength(block); !found || prefixLen < largest {
+			largest, found = prefixLen, true
+		}
+	}
+	return largest, found
+}
+
+// freeBlockCount counts the IPv4 blocks of a prefix length fitting in the free blocks. Being
+// aligned, every free block of that size lies within exactly one of them.
+func freeBlockCount(free []*net.IPNet, prefixLen int) int64 {
+	var count int64
+	for _, block := range free {
+		if block.IP.To4() == nil {
+			continue
+		}
+		if blockPrefixLen := getNetworkPrefixLength(block); blockPrefixLen <= prefixLen {
+			count += 1 << uint(prefixLen-blockPrefixLen)
+		}
+	}
+	return count
+}
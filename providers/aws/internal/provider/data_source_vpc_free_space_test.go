@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestFreeCidrBlocks(t *testing.T) {
+	t.Parallel()
+
+	free := freeCidrBlocks(
+		[]string{"10.0.0.0/24", "2600:1f18:abcd:1200::/56"},
+		[]string{"10.0.0.0/27", "10.0.0.64/26", "2600:1f18:abcd:1200::/57"},
+	)
+
+	var got []string
+	for _, block := range free {
+		got = append(got, block.String())
+	}
+	expected := []string{"10.0.0.32/27", "10.0.0.128/25", "2600:1f18:abcd:1280::/57"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if prefixLen, ok := largestFreePrefixLength(free); !ok || prefixLen != 25 {
+		t.Errorf("expected a largest free prefix of /25, got /%d (%t)", prefixLen, ok)
+	}
+
+	for prefixLen, expected := range map[int]int64{24: 0, 25: 1, 26: 2, 27: 5, 28: 10} {
+		if got := freeBlockCount(free, prefixLen); got != expected {
+			t.Errorf("/%d: expected %d free blocks, got %d", prefixLen, expected, got)
+		}
+	}
+}
+
+func TestFreeCidrBlocks_Full(t *testing.T) {
+	t.Parallel()
+
+	free := freeCidrBlocks([]string{"10.0.0.0/24"}, []string{"10.0.0.0/25", "10.0.0.128/25"})
+	if len(free) != 0 {
+		t.Errorf("expected no free block, got %v", free)
+	}
+	if _, ok := largestFreePrefixLength(free); ok {
+		t.Error("expected no largest free prefix")
+	}
+}
+
+func TestVpcFreeSpaceDataSource_InvalidPrefixLengths(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "dx_vpc_free_space" "test" {
+  vpc_id         = "vpc-0123456789abcdef0"
+  prefix_lengths = [24, 29]
+}
+`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`value must be between 1 and 28`),
+			},
+		},
+	})
+}
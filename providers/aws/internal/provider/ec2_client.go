@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// ec2API is the part of the EC2 API read by the allocator, so tests can replace the client
+type ec2API interface {
+	DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+	DescribeSubnets(ctx context.Context, params *ec2.DescribeSubnetsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSubnetsOutput, error)
+}
+
+var _ ec2API = &ec2.Client{}
+
+// awsClientConfig holds the client settings of the provider, empty values keeping the SDK
+// defaults: environment variables, shared config files and instance metadata
+type awsClientConfig struct {
+	region  string
+	profile string
+
+	// roleARN, when set, is assumed with the base credentials, e.g. to allocate in the VPC of another account
+	roleARN     string
+	sessionName string
+	externalID  string
+
+	ec2Endpoint string
+
+	retry retryConfig
+}
+
+// retryConfig is the retry policy of the max_retries and max_retry_delay attributes, unset values
+// keeping the SDK defaults
+type retryConfig struct {
+	maxRetries    *int64
+	maxRetryDelay time.Duration
+}
+
+// newRetryConfig reads the retry attributes of the provider
+func newRetryConfig(maxRetries types.Int64, maxRetryDelay types.String) (retryConfig, error) {
+	var retry retryConfig
+	if !maxRetries.IsNull() && !maxRetries.IsUnknown() {
+		value := maxRetries.ValueInt64()
+		retry.maxRetries = &value
+	}
+	if !maxRetryDelay.IsNull() && !maxRetryDelay.IsUnknown() {
+		delay, err := time.ParseDuration(maxRetryDelay.ValueString())
+		if err != nil || delay <= 0 {
+			return retry, fmt.Errorf("max_retry_delay %q must be a positive duration, e.g. 30s or 2m", maxRetryDelay.ValueString())
+		}
+		retry.maxRetryDelay = delay
+	}
+	return retry, nil
+}
+
+// loadAWSConfig resolves the AWS configuration shared by every resource and data source of the provider
+func loadAWSConfig(ctx context.Context, settings awsClientConfig) (aws.Config, error) {
+	var optFns []func(*config.LoadOptions) error
+	if settings.region != "" {
+		optFns = append(optFns, config.WithRegion(settings.region))
+	}
+	if settings.profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(settings.profile))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("unable to load AWS configuration: %w", err)
+	}
+	cfg.Retryer = func() aws.Retryer {
+		return newRetryer(settings.retry)
+	}
+
+	if settings.roleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), settings.roleARN, func(o *stscreds.AssumeRoleOptions) {
+			if settings.sessionName != "" {
+				o.RoleSessionName = settings.sessionName
+			}
+			if settings.externalID != "" {
+				o.ExternalID = aws.String(settings.externalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
+
+// newRetryer returns the retryer of the AWS clients: the SDK standard one, retrying throttling
+// errors such as RequestLimitExceeded with an exponential backoff, and waiting for the Retry-After
+// of the response when set. A Retry-After longer than the maximum delay fails the request rather
+// than waiting.
+func newRetryer(settings retryConfig) aws.Retryer {
+	maxDelay := retry.DefaultMaxBackoff
+	if settings.maxRetryDelay > 0 {
+		maxDelay = settings.maxRetryDelay
+	}
+
+	standard := retry.NewStandard(func(o *retry.StandardOptions) {
+		if settings.maxRetries != nil {
+			o.MaxAttempts = int(*settings.maxRetries) + 1
+		}
+		o.MaxBackoff = maxDelay
+		// A plan throttled as a whole would drain the client-side retry quota long before max_retries
+		o.RateLimiter = ratelimit.None
+	})
+	return &retryAfterRetryer{RetryerV2: standard, maxDelay: maxDelay}
+}
+
+// retryAfterRetryer waits for the Retry-After of throttled responses instead of the backoff
+type retryAfterRetryer struct {
+	aws.RetryerV2
+	maxDelay time.Duration
+}
+
+func (r *retryAfterRetryer) RetryDelay(attempt int, err error) (time.Duration, error) {
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) || respErr.Response == nil {
+		return r.RetryerV2.RetryDelay(attempt, err)
+	}
+
+	delay, ok := retryAfter(respErr.Response.Header.Get("Retry-After"))
+	if !ok {
+		return r.RetryerV2.RetryDelay(attempt, err)
+	}
+	if delay > r.maxDelay {
+		return 0, err
+	}
+	return delay, nil
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// awsAPIErrorDiagnostic describes a failed AWS call, telling throttling, missing permissions and
+// missing resources apart so operators know what to fix
+func awsAPIErrorDiagnostic(err error) diag.Diagnostic {
+	var apiErr smithy.APIError
+	code := ""
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+
+	switch {
+	case retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err).Bool():
+		return diag.NewErrorDiagnostic(
+			"AWS API Throttled",
+			fmt.Sprintf("%s\n\nEC2 kept throttling the requests after the retries of the provider. "+
+				"Lower the Terraform parallelism, or raise max_retries and max_retry_delay in the provider configuration.", err),
+		)
+	case code == "UnauthorizedOperation" || code == "AccessDenied":
+		return diag.NewErrorDiagnostic(
+			"AWS Authorization Failed",
+			fmt.Sprintf("%s\n\nThe identity of the provider needs the ec2:DescribeVpcs and ec2:DescribeSubnets permissions. "+
+				"With assume_role, these are needed by the role, and the identity needs sts:AssumeRole on it.", err),
+		)
+	case code == "AuthFailure" || code == "InvalidClientTokenId" || code == "ExpiredToken":
+		return diag.NewErrorDiagnostic(
+			"AWS Authentication Failed",
+			fmt.Sprintf("%s\n\nCheck the credentials of the provider, e.g. profile or the AWS_* environment variables.", err),
+		)
+	case strings.HasSuffix(code, ".NotFound"):
+		return diag.NewErrorDiagnostic(
+			"AWS Resource Not Found",
+			fmt.Sprintf("%s\n\nCheck the ID, and that the resource exists in the account and the region of the provider (aws_region, assume_role).", err),
+		)
+	default:
+		return diag.NewErrorDiagnostic("AWS API Error", err.Error())
+	}
+}
+
+// newEC2Client creates the EC2 client of the provider, sending requests to endpoint when set
+func newEC2Client(cfg aws.Config, endpoint string) (ec2API, error) {
+	if endpoint == "" {
+		return ec2.NewFromConfig(cfg), nil
+	}
+
+	parsed, err := url.Parse(endpoint)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("EC2 endpoint %q is not a valid URL", endpoint)
+	}
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		o.BaseEndpoint = aws.String(endpoint)
+	}), nil
+}
@@ -0,0 +1,275 @@
+package provider
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// isolateAWSEnv keeps the credentials and config files of the machine out of the test
+func isolateAWSEnv(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAFAKEBASEACCOUNT0")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+}
+
+func TestNewEC2Client(t *testing.T) {
+	isolateAWSEnv(t)
+
+	cfg, err := loadAWSConfig(t.Context(), awsClientConfig{region: "eu-south-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Region != "eu-south-1" {
+		t.Errorf("expected region eu-south-1, got %s", cfg.Region)
+	}
+
+	if _, err := newEC2Client(cfg, "https://ec2.eu-south-1.amazonaws.com"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if _, err := newEC2Client(cfg, "ec2.eu-south-1.amazonaws.com"); err == nil || !strings.Contains(err.Error(), "is not a valid URL") {
+		t.Errorf("expected an invalid URL error, got %v", err)
+	}
+}
+
+func TestProviderAssumeRole(t *testing.T) {
+	isolateAWSEnv(t)
+	fake := newFakeEC2(t)
+	t.Setenv("AWS_ENDPOINT_URL_STS", fake.server.URL)
+	vpcID := fake.addVpc("10.0.0.0/24")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "dx" {
+						aws_region = "eu-west-1"
+
+						assume_role {
+							role_arn     = "arn:aws:iam::123456789012:role/dx-networking"
+							session_name = "dx-allocator"
+							external_id  = "networking"
+						}
+
+						endpoints {
+							ec2 = %q
+						}
+					}
+
+					data "dx_vpc_free_space" "test" {
+						vpc_id = %q
+					}
+				`, fake.server.URL, vpcID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "free_cidrs.0", "10.0.0.0/24"),
+					func(*terraform.State) error {
+						fake.mu.Lock()
+						defer fake.mu.Unlock()
+						if len(fake.assumeRoleRequests) == 0 {
+							return fmt.Errorf("the role was not assumed")
+						}
+						request := fake.assumeRoleRequests[0]
+						for name, expected := range map[string]string{
+							"RoleArn":         "arn:aws:iam::123456789012:role/dx-networking",
+							"RoleSessionName": "dx-allocator",
+							"ExternalId":      "networking",
+						} {
+							if got := request.Get(name); got != expected {
+								return fmt.Errorf("expected AssumeRole %s %q, got %q", name, expected, got)
+							}
+						}
+						for _, accessKeyID := range fake.accessKeyIDs {
+							if accessKeyID != fakeAssumedAccessKeyID {
+								return fmt.Errorf("expected EC2 requests signed by the assumed role, got access key %q", accessKeyID)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestProviderAWSConfigurationErrors(t *testing.T) {
+	isolateAWSEnv(t)
+
+	tests := []struct {
+		name          string
+		config        string
+		expectedError string
+	}{
+		{
+			name:          "missing role ARN",
+			config:        `assume_role { session_name = "dx" }`,
+			expectedError: `The assume_role block requires the role_arn attribute`,
+		},
+		{
+			name:          "invalid role ARN",
+			config:        `assume_role { role_arn = "dx-networking" }`,
+			expectedError: `must be the ARN of an IAM role`,
+		},
+		{
+			name:          "unknown profile",
+			config:        `profile = "missing"`,
+			expectedError: `unable to load AWS configuration`,
+		},
+		{
+			name:          "invalid retry delay",
+			config:        `max_retry_delay = "30"`,
+			expectedError: `must be a positive duration`,
+		},
+		{
+			name:          "invalid endpoint",
+			config:        `endpoints { ec2 = "ec2.eu-west-1.amazonaws.com" }`,
+			expectedError: `is not a valid URL`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+							provider "dx" {
+								%s
+							}
+
+							data "dx_resource_types" "test" {}
+						`, tt.config),
+						ExpectError: regexp.MustCompile(tt.expectedError),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestNewRetryConfig(t *testing.T) {
+	retry, err := newRetryConfig(types.Int64Value(0), types.StringValue("2m"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *retry.maxRetries != 0 || retry.maxRetryDelay != 2*time.Minute {
+		t.Errorf("expected no retries and a 2m delay, got %d and %s", *retry.maxRetries, retry.maxRetryDelay)
+	}
+	if attempts := newRetryer(retry).MaxAttempts(); attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+
+	for _, delay := range []string{"30", "-1s", "0s"} {
+		if _, err := newRetryConfig(types.Int64Null(), types.StringValue(delay)); err == nil {
+			t.Errorf("expected an error for max_retry_delay %q", delay)
+		}
+	}
+}
+
+func TestEC2ClientRetry(t *testing.T) {
+	isolateAWSEnv(t)
+	maxRetries := int64(2)
+
+	newClient := func(t *testing.T, fake *fakeEC2, retry retryConfig) ec2API {
+		t.Helper()
+		cfg, err := loadAWSConfig(t.Context(), awsClientConfig{region: "eu-west-1", retry: retry})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		client, err := newEC2Client(cfg, fake.server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return client
+	}
+
+	t.Run("throttled then served", func(t *testing.T) {
+		fake := newFakeEC2(t)
+		vpcID := fake.addVpc("10.0.0.0/16")
+		fake.throttle(2, "0")
+
+		if _, err := getVpcLayout(t.Context(), newClient(t, fake, retryConfig{maxRetries: &maxRetries}), vpcID); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		// 3 DescribeVpcs and 1 DescribeSubnets
+		if requests := fake.requestCount(); requests != 4 {
+			t.Errorf("expected 4 requests, got %d", requests)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		fake := newFakeEC2(t)
+		vpcID := fake.addVpc("10.0.0.0/16")
+		fake.throttle(3, "0")
+
+		_, err := getVpcLayout(t.Context(), newClient(t, fake, retryConfig{maxRetries: &maxRetries}), vpcID)
+		if summary := awsAPIErrorDiagnostic(err).Summary(); summary != "AWS API Throttled" {
+			t.Errorf("expected a throttling diagnostic, got %q for %v", summary, err)
+		}
+	})
+
+	t.Run("Retry-After beyond the maximum delay", func(t *testing.T) {
+		fake := newFakeEC2(t)
+		vpcID := fake.addVpc("10.0.0.0/16")
+		fake.throttle(1, "120")
+
+		_, err := getVpcLayout(t.Context(), newClient(t, fake, retryConfig{maxRetries: &maxRetries, maxRetryDelay: time.Second}), vpcID)
+		if err == nil {
+			t.Fatal("expected the throttled request to fail")
+		}
+		if requests := fake.requestCount(); requests != 1 {
+			t.Errorf("expected no retry, got %d requests", requests)
+		}
+	})
+}
+
+func TestAWSAPIErrorDiagnostic(t *testing.T) {
+	for code, expected := range map[string]string{
+		"RequestLimitExceeded":     "AWS API Throttled",
+		"UnauthorizedOperation":    "AWS Authorization Failed",
+		"AuthFailure":              "AWS Authentication Failed",
+		"InvalidSubnetID.NotFound": "AWS Resource Not Found",
+		"InternalError":            "AWS API Error",
+	} {
+		err := fmt.Errorf("unable to describe VPC: %w", &smithy.GenericAPIError{Code: code})
+		if summary := awsAPIErrorDiagnostic(err).Summary(); summary != expected {
+			t.Errorf("%s: expected %q, got %q", code, expected, summary)
+		}
+	}
+}
+
+func TestAvailableSubnetCidrResource_AuthorizationFailed(t *testing.T) {
+	fake := newFakeEC2(t)
+	vpcID := fake.addVpc("10.0.0.0/16")
+	fake.deny()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					resource "dx_available_subnet_cidr" "test" {
+						vpc_id        = %q
+						prefix_length = 24
+					}
+				`, vpcID),
+				ExpectError: regexp.MustCompile(`AWS Authorization Failed(.|\n)*ec2:DescribeVpcs`),
+			},
+		},
+	})
+}
@@ -0,0 +1,263 @@
+package provider
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// fakeEC2PageSize is small so that DescribeSubnets is paginated
+const fakeEC2PageSize = 2
+
+// fakeEC2 serves the DescribeVpcs and DescribeSubnets actions of the EC2 query API from memory,
+// so the resource lifecycle runs without AWS. It also answers the STS AssumeRole action.
+type fakeEC2 struct {
+	mu      sync.Mutex
+	vpcs    map[string][]string
+	subnets []fakeEC2Subnet
+	nextID  int
+	server  *httptest.Server
+
+	// assumeRoleRequests are the parameters of the AssumeRole calls received
+	assumeRoleRequests []url.Values
+	// accessKeyIDs are the access keys signing the EC2 requests, in order
+	accessKeyIDs []string
+
+	// throttled is the number of next EC2 requests answered with RequestLimitExceeded, with the
+	// retryAfter header when set
+	throttled  int
+	retryAfter string
+	// denied answers every EC2 request with UnauthorizedOperation, like a policy missing ec2:Describe*
+	denied bool
+}
+
+type fakeEC2Subnet struct {
+	id        string
+	vpcID     string
+	cidrBlock string
+}
+
+func newFakeEC2(t *testing.T) *fakeEC2 {
+	t.Helper()
+	f := &fakeEC2{vpcs: map[string][]string{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns an EC2 client sending its requests to the fake
+func (f *fakeEC2) client() ec2API {
+	return ec2.New(ec2.Options{
+		BaseEndpoint: aws.String(f.server.URL),
+		Region:       "eu-west-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+}
+
+// providerFactories returns the dx provider reading the VPCs of the fake
+func (f *fakeEC2) providerFactories() map[string]func() (tfprotov6.ProviderServer, error) {
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"dx": providerserver.NewProtocol6WithError(&dxProvider{Version: "test", ec2Client: f.client()}),
+	}
+}
+
+func (f *fakeEC2) addVpc(cidrBlocks ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	vpcID := fmt.Sprintf("vpc-%017d", f.nextID)
+	f.vpcs[vpcID] = cidrBlocks
+	return vpcID
+}
+
+func (f *fakeEC2) addSubnet(vpcID, cidrBlock string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	subnetID := fmt.Sprintf("subnet-%017d", f.nextID)
+	f.subnets = append(f.subnets, fakeEC2Subnet{id: subnetID, vpcID: vpcID, cidrBlock: cidrBlock})
+	return subnetID
+}
+
+func (f *fakeEC2) deleteVpc(vpcID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.vpcs, vpcID)
+	subnets := f.subnets[:0]
+	for _, subnet := range f.subnets {
+		if subnet.vpcID != vpcID {
+			subnets = append(subnets, subnet)
+		}
+	}
+	f.subnets = subnets
+}
+
+func (f *fakeEC2) throttle(requests int, retryAfter string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.throttled = requests
+	f.retryAfter = retryAfter
+}
+
+func (f *fakeEC2) deny() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.denied = true
+}
+
+func (f *fakeEC2) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.accessKeyIDs)
+}
+
+type fakeEC2CidrBlock struct {
+	CidrBlock string `xml:"cidrBlock"`
+	State     string `xml:"cidrBlockState>state"`
+}
+
+type fakeEC2Vpc struct {
+	VpcID        string             `xml:"vpcId"`
+	CidrBlock    string             `xml:"cidrBlock"`
+	Associations []fakeEC2CidrBlock `xml:"cidrBlockAssociationSet>item"`
+}
+
+type fakeEC2SubnetItem struct {
+	SubnetID  string `xml:"subnetId"`
+	VpcID     string `xml:"vpcId"`
+	CidrBlock string `xml:"cidrBlock"`
+}
+
+// fakeAssumedAccessKeyID is the access key of the credentials returned by AssumeRole
+const fakeAssumedAccessKeyID = "ASIAFAKEASSUMEDROLE0"
+
+type fakeSTSAssumeRoleResponse struct {
+	XMLName         xml.Name `xml:"AssumeRoleResponse"`
+	AccessKeyID     string   `xml:"AssumeRoleResult>Credentials>AccessKeyId"`
+	SecretAccessKey string   `xml:"AssumeRoleResult>Credentials>SecretAccessKey"`
+	SessionToken    string   `xml:"AssumeRoleResult>Credentials>SessionToken"`
+	Expiration      string   `xml:"AssumeRoleResult>Credentials>Expiration"`
+	AssumedRoleArn  string   `xml:"AssumeRoleResult>AssumedRoleUser>Arn"`
+}
+
+type fakeEC2DescribeVpcsResponse struct {
+	XMLName xml.Name     `xml:"DescribeVpcsResponse"`
+	Vpcs    []fakeEC2Vpc `xml:"vpcSet>item"`
+}
+
+type fakeEC2DescribeSubnetsResponse struct {
+	XMLName   xml.Name            `xml:"DescribeSubnetsResponse"`
+	Subnets   []fakeEC2SubnetItem `xml:"subnetSet>item"`
+	NextToken string              `xml:"nextToken,omitempty"`
+}
+
+func (f *fakeEC2) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeFakeEC2Error(w, http.StatusBadRequest, "InvalidParameterValue", err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Form.Get("Action") != "AssumeRole" {
+		f.accessKeyIDs = append(f.accessKeyIDs, fakeEC2AccessKeyID(r.Header.Get("Authorization")))
+
+		if f.denied {
+			writeFakeEC2Error(w, http.StatusForbidden, "UnauthorizedOperation", "You are not authorized to perform this operation.")
+			return
+		}
+		if f.throttled > 0 {
+			f.throttled--
+			if f.retryAfter != "" {
+				w.Header().Set("Retry-After", f.retryAfter)
+			}
+			writeFakeEC2Error(w, http.StatusServiceUnavailable, "RequestLimitExceeded", "Request limit exceeded.")
+			return
+		}
+	}
+
+	switch r.Form.Get("Action") {
+	case "AssumeRole":
+		f.assumeRoleRequests = append(f.assumeRoleRequests, r.Form)
+		writeFakeEC2Response(w, fakeSTSAssumeRoleResponse{
+			AccessKeyID:     fakeAssumedAccessKeyID,
+			SecretAccessKey: "secret",
+			SessionToken:    "token",
+			Expiration:      time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			AssumedRoleArn:  r.Form.Get("RoleArn"),
+		})
+
+	case "DescribeVpcs":
+		vpcID := r.Form.Get("VpcId.1")
+		cidrBlocks, ok := f.vpcs[vpcID]
+		if !ok {
+			writeFakeEC2Error(w, http.StatusBadRequest, "InvalidVpcID.NotFound", fmt.Sprintf("The vpc ID '%s' does not exist", vpcID))
+			return
+		}
+		vpc := fakeEC2Vpc{VpcID: vpcID, CidrBlock: cidrBlocks[0]}
+		for _, cidrBlock := range cidrBlocks {
+			vpc.Associations = append(vpc.Associations, fakeEC2CidrBlock{CidrBlock: cidrBlock, State: "associated"})
+		}
+		writeFakeEC2Response(w, fakeEC2DescribeVpcsResponse{Vpcs: []fakeEC2Vpc{vpc}})
+
+	case "DescribeSubnets":
+		var matching []fakeEC2SubnetItem
+		subnetID, vpcID := r.Form.Get("SubnetId.1"), r.Form.Get("Filter.1.Value.1")
+		for _, subnet := range f.subnets {
+			if (subnetID != "" && subnet.id == subnetID) || (vpcID != "" && subnet.vpcID == vpcID) {
+				matching = append(matching, fakeEC2SubnetItem{SubnetID: subnet.id, VpcID: subnet.vpcID, CidrBlock: subnet.cidrBlock})
+			}
+		}
+		if subnetID != "" && len(matching) == 0 {
+			writeFakeEC2Error(w, http.StatusBadRequest, "InvalidSubnetID.NotFound", fmt.Sprintf("The subnet ID '%s' does not exist", subnetID))
+			return
+		}
+		sort.Slice(matching, func(i, j int) bool { return matching[i].SubnetID < matching[j].SubnetID })
+
+		start, _ := strconv.Atoi(r.Form.Get("NextToken"))
+		end := min(start+fakeEC2PageSize, len(matching))
+		response := fakeEC2DescribeSubnetsResponse{Subnets: matching[start:end]}
+		if end < len(matching) {
+			response.NextToken = strconv.Itoa(end)
+		}
+		writeFakeEC2Response(w, response)
+
+	default:
+		writeFakeEC2Error(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("The action %s is not valid for this web service", r.Form.Get("Action")))
+	}
+}
+
+// fakeEC2AccessKeyID returns the access key of a SigV4 Authorization header, empty when unsigned
+func fakeEC2AccessKeyID(authorization string) string {
+	_, credential, found := strings.Cut(authorization, "Credential=")
+	if !found {
+		return ""
+	}
+	accessKeyID, _, _ := strings.Cut(credential, "/")
+	return accessKeyID
+}
+
+func writeFakeEC2Response(w http.ResponseWriter, response any) {
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	_ = xml.NewEncoder(w).Encode(response)
+}
+
+func writeFakeEC2Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors><RequestID>fake</RequestID></Response>", code, message)
+}
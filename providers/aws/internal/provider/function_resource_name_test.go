@@ -371,3 +371,77 @@ func TestResourceNameFunction_InvalidPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceNameFunction_MergedConfiguration(t *testing.T) {
+	t.Parallel()
+	// Provider functions do not see the provider block, so shared values are
+	// passed through a merged local map. Keys in the second map win.
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+        locals {
+          naming_config = {
+						prefix = "dx",
+						environment = "d",
+						region = "eu-west-1",
+						domain = "test",
+					}
+        }
+
+        output "test" {
+          value = provider::dx::resource_name(merge(local.naming_config, {
+						name = "example",
+						resource_type = "lambda_function",
+						instance_number = 1
+					}))
+        }
+
+        output "override" {
+          value = provider::dx::resource_name(merge(local.naming_config, {
+						region = "eu-central-1",
+						name = "example",
+						resource_type = "lambda_function",
+						instance_number = 2
+					}))
+        }
+        `,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact("dx-d-eu-test-example-lambda-01")),
+					statecheck.ExpectKnownOutputValue("override", knownvalue.StringExact("dx-d-euc1-test-example-lambda-02")),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_MissingKeyHint(t *testing.T) {
+	t.Parallel()
+	// Test that a missing key error names the key and hints at merge()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+        output "test" {
+          value = provider::dx::resource_name({
+						environment = "d",
+						region = "eu",
+						name = "example",
+						resource_type = "s3_bucket",
+						instance_number = "1"
+					})
+        }
+        `,
+				ExpectError: regexp.MustCompile(`required key 'prefix' is missing(.|\n)*merge\(\)`),
+			},
+		},
+	})
+}
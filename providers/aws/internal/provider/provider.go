@@ -3,21 +3,41 @@ package provider
 
 import (
 	"context"
+	"regexp"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 )
 
-var _ provider.Provider = &dxProvider{}
+var (
+	_ provider.Provider                   = &dxProvider{}
+	_ provider.ProviderWithValidateConfig = &dxProvider{}
+)
+
+// roleARNPattern matches IAM role ARNs of every AWS partition
+var roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/.+$`)
 
 type dxProvider struct {
 	Version string
+
+	// ec2Client replaces the EC2 client built from the configuration, nil outside of tests
+	ec2Client ec2API
+}
+
+// dxProviderData is shared with resources and data sources once the provider is configured
+type dxProviderData struct {
+	// ec2Client is built once from the AWS configuration of the provider
+	ec2Client ec2API
 }
 
 type dxProviderModel struct {
@@ -25,6 +45,24 @@ type dxProviderModel struct {
 	Domain      types.String `tfsdk:"domain"`
 	Environment types.String `tfsdk:"environment"`
 	Region      types.String `tfsdk:"region"`
+
+	AWSRegion  types.String `tfsdk:"aws_region"`
+	Profile    types.String `tfsdk:"profile"`
+	AssumeRole types.Object `tfsdk:"assume_role"`
+	Endpoints  types.Object `tfsdk:"endpoints"`
+
+	MaxRetries    types.Int64  `tfsdk:"max_retries"`
+	MaxRetryDelay types.String `tfsdk:"max_retry_delay"`
+}
+
+type assumeRoleModel struct {
+	RoleARN     types.String `tfsdk:"role_arn"`
+	SessionName types.String `tfsdk:"session_name"`
+	ExternalID  types.String `tfsdk:"external_id"`
+}
+
+type endpointsModel struct {
+	EC2 types.String `tfsdk:"ec2"`
 }
 
 // New creates a new instance of the provider
@@ -76,16 +114,149 @@ func (p *dxProvider) Schema(ctx context.Context, req provider.SchemaRequest, res
 					}...),
 				},
 			},
+			"aws_region": schema.StringAttribute{
+				Optional:    true,
+				Description: "AWS region the API requests are sent to, e.g. eu-west-1. Defaults to the AWS_REGION environment variable or the shared config files",
+			},
+			"profile": schema.StringAttribute{
+				Optional:    true,
+				Description: "Profile of the shared config and credentials files to authenticate with. Defaults to the AWS_PROFILE environment variable",
+			},
+			"max_retries": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Maximum number of retries of a throttled or failed AWS request. Defaults to 2",
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"max_retry_delay": schema.StringAttribute{
+				Optional:    true,
+				Description: "Maximum delay between retries, e.g. 30s or 2m. Requests whose Retry-After is longer fail without waiting. Defaults to 20s",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"assume_role": schema.SingleNestedBlock{
+				Description: "IAM role assumed to read the VPCs, e.g. of a workload account from a central networking account",
+				Attributes: map[string]schema.Attribute{
+					"role_arn": schema.StringAttribute{
+						Optional:    true,
+						Description: "ARN of the IAM role to assume",
+						Validators: []validator.String{
+							stringvalidator.RegexMatches(roleARNPattern, "must be the ARN of an IAM role, e.g. arn:aws:iam::123456789012:role/name"),
+						},
+					},
+					"session_name": schema.StringAttribute{
+						Optional:    true,
+						Description: "Name of the session of the assumed role, reported in CloudTrail",
+					},
+					"external_id": schema.StringAttribute{
+						Optional:    true,
+						Description: "External ID required by the trust policy of the role",
+					},
+				},
+			},
+			"endpoints": schema.SingleNestedBlock{
+				Description: "Custom endpoints of the AWS services, e.g. VPC interface endpoints or a local stand-in",
+				Attributes: map[string]schema.Attribute{
+					"ec2": schema.StringAttribute{
+						Optional:    true,
+						Description: "URL of the EC2 endpoint",
+					},
+				},
+			},
 		},
 	}
 }
 
+func (p *dxProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var config dxProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if _, err := newRetryConfig(config.MaxRetries, config.MaxRetryDelay); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("max_retry_delay"), "Invalid retry configuration", err.Error())
+	}
+
+	if !config.AssumeRole.IsNull() && !config.AssumeRole.IsUnknown() {
+		var assumeRole assumeRoleModel
+		resp.Diagnostics.Append(config.AssumeRole.As(ctx, &assumeRole, basetypes.ObjectAsOptions{})...)
+		if assumeRole.RoleARN.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("assume_role").AtName("role_arn"),
+				"Missing role ARN",
+				"The assume_role block requires the role_arn attribute",
+			)
+		}
+	}
+}
+
 func (p *dxProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var config dxProviderModel
 	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	ec2Client, diags := p.newEC2Client(ctx, config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	providerData := &dxProviderData{ec2Client: ec2Client}
+	resp.DataSourceData = providerData
+	resp.ResourceData = providerData
+}
+
+// newEC2Client resolves the AWS configuration once and builds the EC2 client shared by every
+// resource and data source
+func (p *dxProvider) newEC2Client(ctx context.Context, config dxProviderModel) (ec2API, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if p.ec2Client != nil {
+		return p.ec2Client, diags
+	}
+
+	settings := awsClientConfig{
+		region:  config.AWSRegion.ValueString(),
+		profile: config.Profile.ValueString(),
+	}
+	if !config.AssumeRole.IsNull() && !config.AssumeRole.IsUnknown() {
+		var assumeRole assumeRoleModel
+		diags.Append(config.AssumeRole.As(ctx, &assumeRole, basetypes.ObjectAsOptions{})...)
+		settings.roleARN = assumeRole.RoleARN.ValueString()
+		settings.sessionName = assumeRole.SessionName.ValueString()
+		settings.externalID = assumeRole.ExternalID.ValueString()
+	}
+	if !config.Endpoints.IsNull() && !config.Endpoints.IsUnknown() {
+		var endpoints endpointsModel
+		diags.Append(config.Endpoints.As(ctx, &endpoints, basetypes.ObjectAsOptions{})...)
+		settings.ec2Endpoint = endpoints.EC2.ValueString()
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	retry, err := newRetryConfig(config.MaxRetries, config.MaxRetryDelay)
+	if err != nil {
+		diags.AddAttributeError(path.Root("max_retry_delay"), "Invalid retry configuration", err.Error())
+		return nil, diags
+	}
+	settings.retry = retry
+
+	awsConfig, err := loadAWSConfig(ctx, settings)
+	if err != nil {
+		diags.AddError("AWS Configuration Error", err.Error())
+		return nil, diags
+	}
+
+	ec2Client, err := newEC2Client(awsConfig, settings.ec2Endpoint)
+	if err != nil {
+		diags.AddAttributeError(path.Root("endpoints").AtName("ec2"), "Invalid EC2 endpoint", err.Error())
+		return nil, diags
+	}
+	return ec2Client, diags
 }
 
 // Resources defines the resources available in this provider
@@ -97,7 +268,10 @@ func (p *dxProvider) Resources(ctx context.Context) []func() resource.Resource {
 
 // DataSources defines the data sources available in this provider
 func (p *dxProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return nil
+	return []func() datasource.DataSource{
+		NewResourceTypesDataSource,
+		NewVpcFreeSpaceDataSource,
+	}
 }
 
 // Functions defines the functions available in this provider
@@ -3,16 +3,20 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
+	"strings"
 
 	"github.com/apparentlymart/go-cidr/cidr"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
@@ -24,6 +28,10 @@ import (
 
 // Make sure it implements the Resource interface
 var _ resource.Resource = &availableSubnetCidrResource{}
+var _ resource.ResourceWithValidateConfig = &availableSubnetCidrResource{}
+var _ resource.ResourceWithModifyPlan = &availableSubnetCidrResource{}
+var _ resource.ResourceWithImportState = &availableSubnetCidrResource{}
+var _ resource.ResourceWithConfigure = &availableSubnetCidrResource{}
 
 func NewAvailableSubnetCidrResource() resource.Resource {
 	return &availableSubnetCidrResource{}
@@ -31,16 +39,36 @@ func NewAvailableSubnetCidrResource() resource.Resource {
 
 // Resource definition
 type availableSubnetCidrResource struct {
+	// ec2Client is the EC2 client configured in the provider
+	ec2Client ec2API
 }
 
 // Resource model
 type availableSubnetCidrResourceModel struct {
-	ID           frameworkTypes.String `tfsdk:"id"`
-	VpcID        frameworkTypes.String `tfsdk:"vpc_id"`
-	PrefixLength frameworkTypes.Int64  `tfsdk:"prefix_length"`
-	CidrBlock    frameworkTypes.String `tfsdk:"cidr_block"`
+	ID               frameworkTypes.String `tfsdk:"id"`
+	VpcID            frameworkTypes.String `tfsdk:"vpc_id"`
+	PrefixLength     frameworkTypes.Int64  `tfsdk:"prefix_length"`
+	CidrBlock        frameworkTypes.String `tfsdk:"cidr_block"`
+	Ipv6PrefixLength frameworkTypes.Int64  `tfsdk:"ipv6_prefix_length"`
+	Ipv6CidrBlock    frameworkTypes.String `tfsdk:"ipv6_cidr_block"`
+	Status           frameworkTypes.String `tfsdk:"status"`
+	WithinCidr       frameworkTypes.String `tfsdk:"within_cidr"`
+	ExcludeCidrs     frameworkTypes.Set    `tfsdk:"exclude_cidrs"`
 }
 
+// Values of the status attribute, set by Read comparing the block with the current VPC
+const (
+	cidrStatusOK                  = "ok"
+	cidrStatusOverlapping         = "overlapping"
+	cidrStatusOutsideAddressSpace = "outside_address_space"
+)
+
+// subnetIDPattern matches the AWS subnet IDs accepted by ImportState
+var subnetIDPattern = regexp.MustCompile(`^subnet-[a-zA-Z0-9]+$`)
+
+// errVpcNotFound is returned when the VPC doesn't exist (anymore)
+var errVpcNotFound = errors.New("VPC not found")
+
 func (r *availableSubnetCidrResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_available_subnet_cidr"
 }
@@ -71,8 +99,8 @@ func (r *availableSubnetCidrResource) Schema(ctx context.Context, req resource.S
 				},
 			},
 			"prefix_length": schema.Int64Attribute{
-				Description: "The desired prefix length for the new subnet CIDR (e.g., 24 for /24). Must be larger than the VPC prefix and smaller or equal to 28.",
-				Required:    true,
+				Description: "The desired prefix length for the new subnet IPv4 CIDR (e.g., 24 for /24). Must be larger than the VPC prefix and smaller or equal to 28. At least one of prefix_length and ipv6_prefix_length must be set.",
+				Optional:    true,
 				Validators: []validator.Int64{
 					int64validator.Between(1, 28), // AWS subnet limits
 				},
@@ -89,105 +117,220 @@ func (r *availableSubnetCidrResource) Schema(ctx context.Context, req resource.S
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"ipv6_prefix_length": schema.Int64Attribute{
+				Description: "The desired prefix length for the new subnet IPv6 CIDR (e.g., 64 for /64), allocated from the IPv6 CIDR blocks of a dual-stack VPC. Must be one of 44, 48, 52, 56, 60 or 64.",
+				Optional:    true,
+				Validators: []validator.Int64{
+					int64validator.OneOf(44, 48, 52, 56, 60, 64), // AWS IPv6 subnets are /44 to /64, in steps of 4
+				},
+				PlanModifiers: []planmodifier.Int64{
+					prefixLengthRequiresReplace(),
+				},
+			},
+			"ipv6_cidr_block": schema.StringAttribute{
+				Description: "The allocated available IPv6 CIDR block, if ipv6_prefix_length is set.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"within_cidr": schema.StringAttribute{
+				Description: "Range of the VPC CIDR blocks where the block is allocated, in CIDR notation. Defaults to all the VPC CIDR blocks.",
+				Optional:    true,
+			},
+			"exclude_cidrs": schema.SetAttribute{
+				Description: "Ranges the block must not overlap, in CIDR notation (e.g. peering ranges or on-premises networks).",
+				Optional:    true,
+				ElementType: frameworkTypes.StringType,
+			},
+			"status": schema.StringAttribute{
+				Description: "Whether the allocated block still fits the VPC: ok, overlapping (a subnet other than the one using this block overlaps it) or outside_address_space (the VPC CIDR blocks no longer contain it).",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 	}
 }
 
-// Configure prepares the AWS client
+// Configure reads the EC2 client configured in the provider
 func (r *availableSubnetCidrResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.ec2Client = providerData.ec2Client
 }
 
-func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var plan availableSubnetCidrResourceModel
-	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+// ValidateConfig checks the ranges restricting the allocation
+func (r *availableSubnetCidrResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data availableSubnetCidrResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Create AWS config
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"AWS Configuration Error",
-			"Unable to load AWS configuration: "+err.Error(),
+	if data.PrefixLength.IsNull() && data.Ipv6PrefixLength.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Missing Prefix Length",
+			"At least one of prefix_length and ipv6_prefix_length must be set",
 		)
-		return
 	}
 
-	// Create EC2 client
-	ec2Client := ec2.NewFromConfig(cfg)
+	_, _, diags := allocationRanges(ctx, data)
+	resp.Diagnostics.Append(diags...)
+}
 
-	// Get VPC information
-	vpcID := plan.VpcID.ValueString()
-	prefixLength := int(plan.PrefixLength.ValueInt64())
+// ModifyPlan checks within_cidr against the VPC while planning a new block, rather than failing at apply
+func (r *availableSubnetCidrResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if !req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
 
-	tflog.Debug(ctx, "Looking for available CIDR in VPC", map[string]interface{}{
-		"vpc_id":        vpcID,
-		"prefix_length": prefixLength,
-	})
+	var plan availableSubnetCidrResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.VpcID.IsUnknown() || plan.WithinCidr.IsNull() || plan.WithinCidr.IsUnknown() {
+		return
+	}
 
-	// Describe the VPC to get its CIDR blocks
-	vpcResult, err := ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
-		VpcIds: []string{vpcID},
-	})
+	// The VPC may not exist yet, or not be readable while planning: leave the check to apply
+	layout, err := getVpcLayout(ctx, r.ec2Client, plan.VpcID.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError(
-			"AWS API Error",
-			"Unable to describe VPC: "+err.Error(),
-		)
+		tflog.Debug(ctx, "Skipping within_cidr validation", map[string]interface{}{"error": err.Error()})
 		return
 	}
 
-	if len(vpcResult.Vpcs) == 0 {
-		resp.Diagnostics.AddError(
-			"VPC Not Found",
-			fmt.Sprintf("VPC with ID %s not found", vpcID),
+	if !cidrWithinVpc(plan.WithinCidr.ValueString(), append(layout.cidrBlocks, layout.ipv6CidrBlocks...)) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("within_cidr"),
+			"Range Outside VPC",
+			fmt.Sprintf("within_cidr %s is not inside the CIDR blocks of VPC %s", plan.WithinCidr.ValueString(), plan.VpcID.ValueString()),
 		)
+	}
+}
+
+func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan availableSubnetCidrResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	vpc := vpcResult.Vpcs[0]
-	if len(vpc.CidrBlockAssociationSet) == 0 {
-		resp.Diagnostics.AddError(
-			"VPC CIDR Error",
-			"VPC has no CIDR blocks associated",
-		)
+	// Get VPC information
+	vpcID := plan.VpcID.ValueString()
+
+	withinCIDR, excludedCIDRs, diags := allocationRanges(ctx, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Get existing subnets
-	subnetsResult, err := ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
-		Filters: []types.Filter{
-			{
-				Name:   &[]string{"vpc-id"}[0],
-				Values: []string{vpcID},
-			},
-		},
+	tflog.Debug(ctx, "Looking for available CIDR in VPC", map[string]interface{}{
+		"vpc_id":             vpcID,
+		"prefix_length":      plan.PrefixLength.ValueInt64(),
+		"ipv6_prefix_length": plan.Ipv6PrefixLength.ValueInt64(),
 	})
-	if err != nil {
+
+	// Describe the VPC CIDR blocks and the existing subnets
+	layout, err := getVpcLayout(ctx, r.ec2Client, vpcID)
+	if errors.Is(err, errVpcNotFound) {
 		resp.Diagnostics.AddError(
-			"AWS API Error",
-			"Unable to describe subnets: "+err.Error(),
+			"VPC Not Found",
+			fmt.Sprintf("VPC with ID %s not found", vpcID),
 		)
 		return
 	}
+	if err != nil {
+		resp.Diagnostics.Append(awsAPIErrorDiagnostic(err))
+		return
+	}
 
-	// Collect existing CIDR blocks
-	var existingCIDRs []string
-	for _, subnet := range subnetsResult.Subnets {
-		if subnet.CidrBlock != nil {
-			existingCIDRs = append(existingCIDRs, *subnet.CidrBlock)
+	plan.CidrBlock = frameworkTypes.StringNull()
+	if !plan.PrefixLength.IsNull() {
+		if len(layout.cidrBlocks) == 0 {
+			resp.Diagnostics.AddError(
+				"VPC CIDR Error",
+				"VPC has no CIDR blocks associated",
+			)
+			return
 		}
+
+		availableCIDR, diags := selectAvailableCidrBlock(ctx, vpcID, layout.cidrBlocks, layout.subnetCidrBlocks(), excludedCIDRs, withinCIDR, int(plan.PrefixLength.ValueInt64()))
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.CidrBlock = frameworkTypes.StringValue(availableCIDR)
 	}
 
-	// Find available CIDR
-	var availableCIDR string
-	for _, cidrAssoc := range vpc.CidrBlockAssociationSet {
-		if cidrAssoc.CidrBlock == nil {
-			continue
+	plan.Ipv6CidrBlock = frameworkTypes.StringNull()
+	if !plan.Ipv6PrefixLength.IsNull() {
+		if len(layout.ipv6CidrBlocks) == 0 {
+			resp.Diagnostics.AddError(
+				"VPC CIDR Error",
+				"VPC has no IPv6 CIDR blocks associated",
+			)
+			return
+		}
+
+		availableCIDR, diags := selectAvailableCidrBlock(ctx, vpcID, layout.ipv6CidrBlocks, layout.subnetCidrBlocks(), excludedCIDRs, withinCIDR, int(plan.Ipv6PrefixLength.ValueInt64()))
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		plan.Ipv6CidrBlock = frameworkTypes.StringValue(availableCIDR)
+	}
+
+	// Set the result
+	plan.ID = frameworkTypes.StringValue(availableSubnetCidrID(plan))
+	plan.Status = frameworkTypes.StringValue(cidrStatusOK)
+
+	tflog.Debug(ctx, "Found available CIDR", map[string]interface{}{
+		"cidr_block":      plan.CidrBlock.ValueString(),
+		"ipv6_cidr_block": plan.Ipv6CidrBlock.ValueString(),
+	})
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// selectAvailableCidrBlock returns the first block of the given prefix length within the VPC
+// CIDR blocks of one IP version, not overlapping the existing nor the excluded CIDR blocks.
+// withinCIDR restricts the candidates when it has the same IP version.
+func selectAvailableCidrBlock(ctx context.Context, vpcID string, vpcCIDRs, existingCIDRs, excludedCIDRs []string, withinCIDR string, prefixLength int) (string, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+
+	// Blocks of the other IP version never overlap, so they can be checked together
+	existingCIDRs = append(append([]string{}, existingCIDRs...), excludedCIDRs...)
+
+	// Restrict the candidates to within_cidr, if set for this IP version
+	candidateRanges := vpcCIDRs
+	if withinCIDR != "" && isIPv6CIDR(withinCIDR) == isIPv6CIDR(vpcCIDRs[0]) {
+		if !cidrWithinVpc(withinCIDR, vpcCIDRs) {
+			diagnostics.AddAttributeError(
+				path.Root("within_cidr"),
+				"Range Outside VPC",
+				fmt.Sprintf("within_cidr %s is not inside the CIDR blocks of VPC %s", withinCIDR, vpcID),
+			)
+			return "", diagnostics
 		}
+		candidateRanges = []string{withinCIDR}
+	} else {
+		withinCIDR = ""
+	}
 
-		vpcCIDR := *cidrAssoc.CidrBlock
+	// Find available CIDR
+	for _, vpcCIDR := range candidateRanges {
 		tflog.Debug(ctx, "Checking VPC CIDR", map[string]interface{}{
 			"vpc_cidr": vpcCIDR,
 		})
@@ -202,12 +345,13 @@ func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.C
 		newPrefixLength := prefixLength
 		currentPrefixLength := getNetworkPrefixLength(vpcNet)
 
-		if newPrefixLength <= currentPrefixLength {
-			resp.Diagnostics.AddError(
+		// A subnet may take the whole within_cidr range, but not the whole VPC
+		if newPrefixLength < currentPrefixLength || (newPrefixLength == currentPrefixLength && withinCIDR == "") {
+			diagnostics.AddError(
 				"Invalid Prefix Length",
 				fmt.Sprintf("Prefix length %d must be greater than VPC prefix length %d", newPrefixLength, currentPrefixLength),
 			)
-			return
+			return "", diagnostics
 		}
 
 		// Calculate how many subnets we can create
@@ -233,33 +377,16 @@ func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.C
 			}
 
 			if isAvailable {
-				availableCIDR = subnetCIDR
-				break
+				return subnetCIDR, diagnostics
 			}
 		}
-
-		if availableCIDR != "" {
-			break
-		}
-	}
-
-	if availableCIDR == "" {
-		resp.Diagnostics.AddError(
-			"No Available CIDR",
-			fmt.Sprintf("No available CIDR block found in VPC %s with prefix length /%d", vpcID, prefixLength),
-		)
-		return
 	}
 
-	// Set the result
-	plan.ID = frameworkTypes.StringValue(fmt.Sprintf("%s/%d", vpcID, prefixLength))
-	plan.CidrBlock = frameworkTypes.StringValue(availableCIDR)
-
-	tflog.Debug(ctx, "Found available CIDR", map[string]interface{}{
-		"cidr_block": availableCIDR,
-	})
-
-	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+	diagnostics.AddError(
+		"No Available CIDR",
+		fmt.Sprintf("No available CIDR block found in VPC %s with prefix length /%d", vpcID, prefixLength),
+	)
+	return "", diagnostics
 }
 
 func (r *availableSubnetCidrResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -269,22 +396,401 @@ func (r *availableSubnetCidrResource) Read(ctx context.Context, req resource.Rea
 		return
 	}
 
+	// The block itself is tracked only in Terraform state, but the VPC may have changed
+	// since it was allocated: check it still fits, so broken plans surface before the
+	// subnet creation fails
+	vpcID := state.VpcID.ValueString()
+	layout, err := getVpcLayout(ctx, r.ec2Client, vpcID)
+	if errors.Is(err, errVpcNotFound) {
+		resp.Diagnostics.AddWarning(
+			"VPC Not Found",
+			fmt.Sprintf("VPC %s no longer exists, CIDR block %s is removed from state.", vpcID, state.CidrBlock.ValueString()),
+		)
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		// Drift detection is best effort, an unreachable VPC must not break the plan
+		resp.Diagnostics.AddWarning(
+			"Subnet CIDR Drift Not Checked",
+			fmt.Sprintf("Unable to read VPC %s, keeping the prior state: %s", vpcID, err),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+		return
+	}
+
+	// The status reports the first drifted block, each one gets a warning
+	status := cidrStatusOK
+	for _, cidrBlock := range []frameworkTypes.String{state.CidrBlock, state.Ipv6CidrBlock} {
+		if cidrBlock.IsNull() || cidrBlock.ValueString() == "" {
+			continue
+		}
+
+		blockStatus, detail := cidrBlockStatus(cidrBlock.ValueString(), append(layout.cidrBlocks, layout.ipv6CidrBlocks...), layout.subnets)
+		if blockStatus == cidrStatusOK {
+			continue
+		}
+		resp.Diagnostics.AddWarning(
+			"Subnet CIDR Drift Detected",
+			fmt.Sprintf("CIDR block %s of VPC %s %s.", cidrBlock.ValueString(), vpcID, detail),
+		)
+		if status == cidrStatusOK {
+			status = blockStatus
+		}
+	}
+	state.Status = frameworkTypes.StringValue(status)
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *availableSubnetCidrResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-	resp.Diagnostics.AddError(
-		"Update Not Supported",
-		"Updating available_subnet_cidr resource is not supported",
-	)
+	var plan availableSubnetCidrResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// vpc_id and prefix_length require replacement, the other attributes only apply when the
+	// block is allocated: keep the allocated block
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
 func (r *availableSubnetCidrResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
 	// Nothing to delete - this resource just calculates available CIDRs
 }
 
+// ImportState imports the CIDR blocks of an existing subnet into Terraform state.
+// The import ID must be the ID of the subnet, in the format subnet-xxxxxxxxx.
+func (r *availableSubnetCidrResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	subnetID := strings.TrimSpace(req.ID)
+	if !subnetIDPattern.MatchString(subnetID) {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf("The import ID must be an AWS subnet ID in the format subnet-xxxxxxxxx, got: %s", req.ID),
+		)
+		return
+	}
+
+	subnetsResult, err := r.ec2Client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: []string{subnetID},
+	})
+	var apiErr smithy.APIError
+	if (errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidSubnetID.NotFound") || (err == nil && len(subnetsResult.Subnets) == 0) {
+		resp.Diagnostics.AddError(
+			"Subnet Not Found",
+			fmt.Sprintf("Subnet with ID %s not found", subnetID),
+		)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.Append(awsAPIErrorDiagnostic(fmt.Errorf("unable to describe subnet %s: %w", subnetID, err)))
+		return
+	}
+
+	subnet := subnetsResult.Subnets[0]
+	layout := newSubnetLayout(subnet)
+
+	data := availableSubnetCidrResourceModel{
+		PrefixLength:     frameworkTypes.Int64Null(),
+		CidrBlock:        frameworkTypes.StringNull(),
+		Ipv6PrefixLength: frameworkTypes.Int64Null(),
+		Ipv6CidrBlock:    frameworkTypes.StringNull(),
+		ExcludeCidrs:     frameworkTypes.SetNull(frameworkTypes.StringType),
+	}
+	if subnet.VpcId != nil {
+		data.VpcID = frameworkTypes.StringValue(*subnet.VpcId)
+	}
+
+	// Dual-stack subnets have an IPv4 and an IPv6 block, keep the first of each
+	cidrBlocks := []string{layout.cidrBlock}
+	if len(layout.ipv6CidrBlocks) > 0 {
+		cidrBlocks = append(cidrBlocks, layout.ipv6CidrBlocks[0])
+	}
+	for _, cidrBlock := range cidrBlocks {
+		if cidrBlock == "" {
+			continue
+		}
+		_, block, err := net.ParseCIDR(cidrBlock)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Parse Error",
+				fmt.Sprintf("Failed to parse CIDR '%s' returned by AWS: %s", cidrBlock, err),
+			)
+			return
+		}
+
+		if block.IP.To4() == nil {
+			data.Ipv6PrefixLength = frameworkTypes.Int64Value(int64(getNetworkPrefixLength(block)))
+			data.Ipv6CidrBlock = frameworkTypes.StringValue(cidrBlock)
+		} else {
+			data.PrefixLength = frameworkTypes.Int64Value(int64(getNetworkPrefixLength(block)))
+			data.CidrBlock = frameworkTypes.StringValue(cidrBlock)
+		}
+	}
+
+	if data.CidrBlock.IsNull() && data.Ipv6CidrBlock.IsNull() {
+		resp.Diagnostics.AddError(
+			"Subnet Configuration Error",
+			fmt.Sprintf("Subnet %s has no CIDR block associated.", subnetID),
+		)
+		return
+	}
+
+	data.ID = frameworkTypes.StringValue(availableSubnetCidrID(data))
+	data.Status = frameworkTypes.StringValue(cidrStatusOK)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	tflog.Info(ctx, "Imported available subnet CIDR resource", map[string]interface{}{
+		"subnet_id":       subnetID,
+		"vpc_id":          data.VpcID.ValueString(),
+		"prefix_length":   data.PrefixLength.ValueInt64(),
+		"cidr_block":      data.CidrBlock.ValueString(),
+		"ipv6_cidr_block": data.Ipv6CidrBlock.ValueString(),
+	})
+}
+
+// availableSubnetCidrID generates the ID of the resource from its VPC and IPv4 prefix length,
+// or from the IPv6 one when only that is allocated.
+// Format: {vpcId}/{prefixLength} or {vpcId}/ipv6/{ipv6PrefixLength}
+func availableSubnetCidrID(data availableSubnetCidrResourceModel) string {
+	if data.PrefixLength.IsNull() {
+		return fmt.Sprintf("%s/ipv6/%d", data.VpcID.ValueString(), data.Ipv6PrefixLength.ValueInt64())
+	}
+	return fmt.Sprintf("%s/%d", data.VpcID.ValueString(), data.PrefixLength.ValueInt64())
+}
+
 // Helper functions
 
+// vpcLayout holds the CIDR blocks and the subnets of a VPC
+type vpcLayout struct {
+	cidrBlocks     []string
+	ipv6CidrBlocks []string
+	subnets        []subnetLayout
+}
+
+// subnetLayout holds the CIDR blocks of a subnet
+type subnetLayout struct {
+	id             string
+	cidrBlock      string
+	ipv6CidrBlocks []string
+}
+
+// subnetCidrBlocks returns the IPv4 and IPv6 CIDR blocks used by the subnets of the VPC
+func (l *vpcLayout) subnetCidrBlocks() []string {
+	var cidrBlocks []string
+	for _, subnet := range l.subnets {
+		if subnet.cidrBlock != "" {
+			cidrBlocks = append(cidrBlocks, subnet.cidrBlock)
+		}
+		cidrBlocks = append(cidrBlocks, subnet.ipv6CidrBlocks...)
+	}
+	return cidrBlocks
+}
+
+// getVpcLayout describes the CIDR blocks and the subnets of a VPC, returning errVpcNotFound
+// when the VPC doesn't exist
+func getVpcLayout(ctx context.Context, ec2Client ec2API, vpcID string) (*vpcLayout, error) {
+	vpcResult, err := ec2Client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		VpcIds: []string{vpcID},
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidVpcID.NotFound" {
+		return nil, errVpcNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe VPC: %w", err)
+	}
+	if len(vpcResult.Vpcs) == 0 {
+		return nil, errVpcNotFound
+	}
+
+	layout := &vpcLayout{}
+	for _, cidrAssoc := range vpcResult.Vpcs[0].CidrBlockAssociationSet {
+		if cidrAssoc.CidrBlock != nil {
+			layout.cidrBlocks = append(layout.cidrBlocks, *cidrAssoc.CidrBlock)
+		}
+	}
+	for _, cidrAssoc := range vpcResult.Vpcs[0].Ipv6CidrBlockAssociationSet {
+		if cidrAssoc.Ipv6CidrBlock != nil && isAssociated(cidrAssoc.Ipv6CidrBlockState) {
+			layout.ipv6CidrBlocks = append(layout.ipv6CidrBlocks, *cidrAssoc.Ipv6CidrBlock)
+		}
+	}
+
+	// Every page must be read, a subnet missed would be handed out again
+	paginator := ec2.NewDescribeSubnetsPaginator(ec2Client, &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{
+			{
+				Name:   &[]string{"vpc-id"}[0],
+				Values: []string{vpcID},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		subnetsResult, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe subnets: %w", err)
+		}
+
+		for _, subnet := range subnetsResult.Subnets {
+			layout.subnets = append(layout.subnets, newSubnetLayout(subnet))
+		}
+	}
+
+	return layout, nil
+}
+
+// newSubnetLayout returns the CIDR blocks of a subnet, skipping the disassociated IPv6 ones
+func newSubnetLayout(subnet types.Subnet) subnetLayout {
+	current := subnetLayout{}
+	if subnet.SubnetId != nil {
+		current.id = *subnet.SubnetId
+	}
+	if subnet.CidrBlock != nil {
+		current.cidrBlock = *subnet.CidrBlock
+	}
+	for _, cidrAssoc := range subnet.Ipv6CidrBlockAssociationSet {
+		if cidrAssoc.Ipv6CidrBlock != nil && (cidrAssoc.Ipv6CidrBlockState == nil || cidrAssoc.Ipv6CidrBlockState.State != types.SubnetCidrBlockStateCodeDisassociated) {
+			current.ipv6CidrBlocks = append(current.ipv6CidrBlocks, *cidrAssoc.Ipv6CidrBlock)
+		}
+	}
+	return current
+}
+
+// cidrBlockStatus checks an allocated block against the current VPC layout. The block must still
+// be within a VPC CIDR block, and may only overlap a subnet using exactly that block, which is
+// the subnet it was allocated for. The detail describes a status other than ok.
+func cidrBlockStatus(cidrBlock string, vpcCIDRs []string, subnets []subnetLayout) (string, string) {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return cidrStatusOutsideAddressSpace, "is not a valid CIDR block"
+	}
+
+	if !cidrWithinVpc(block.String(), vpcCIDRs) {
+		return cidrStatusOutsideAddressSpace, "is no longer within the CIDR blocks of the VPC"
+	}
+
+	for _, subnet := range subnets {
+		for _, subnetCIDR := range append([]string{subnet.cidrBlock}, subnet.ipv6CidrBlocks...) {
+			if subnetCIDR != block.String() && cidrOverlaps(subnetCIDR, block.String()) {
+				return cidrStatusOverlapping, fmt.Sprintf("overlaps subnet %s (%s)", subnet.id, subnetCIDR)
+			}
+		}
+	}
+
+	return cidrStatusOK, ""
+}
+
+// isAssociated reports whether a VPC IPv6 CIDR block is usable, i.e. not being or already disassociated
+func isAssociated(state *types.VpcCidrBlockState) bool {
+	if state == nil {
+		return true
+	}
+	return state.State != types.VpcCidrBlockStateCodeDisassociating && state.State != types.VpcCidrBlockStateCodeDisassociated
+}
+
+// isIPv6CIDR reports whether a CIDR block is an IPv6 one
+func isIPv6CIDR(cidrBlock string) bool {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	return err == nil && block.IP.To4() == nil
+}
+
+// cidrWithinVpc reports whether a range is inside one of the VPC CIDR blocks
+func cidrWithinVpc(cidrBlock string, vpcCIDRs []string) bool {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return false
+	}
+
+	for _, vpcCIDR := range vpcCIDRs {
+		_, vpcNet, err := net.ParseCIDR(vpcCIDR)
+		if err != nil {
+			continue
+		}
+		if getNetworkPrefixLength(vpcNet) <= getNetworkPrefixLength(block) && vpcNet.Contains(block.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// allocationRanges returns within_cidr and exclude_cidrs, checking they are valid ranges.
+// Unknown values are skipped, so the same checks run at validation time and at apply.
+func allocationRanges(ctx context.Context, data availableSubnetCidrResourceModel) (string, []string, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+	var excluded []string
+
+	if !data.ExcludeCidrs.IsNull() && !data.ExcludeCidrs.IsUnknown() {
+		var values []frameworkTypes.String
+		diagnostics.Append(data.ExcludeCidrs.ElementsAs(ctx, &values, false)...)
+		for _, value := range values {
+			if value.IsUnknown() {
+				continue
+			}
+			if diags := validateCidrAttribute(path.Root("exclude_cidrs"), value.ValueString()); diags.HasError() {
+				diagnostics.Append(diags...)
+				continue
+			}
+			excluded = append(excluded, value.ValueString())
+		}
+	}
+
+	if data.WithinCidr.IsNull() || data.WithinCidr.IsUnknown() {
+		return "", excluded, diagnostics
+	}
+
+	within := data.WithinCidr.ValueString()
+	if diags := validateCidrAttribute(path.Root("within_cidr"), within); diags.HasError() {
+		diagnostics.Append(diags...)
+		return "", excluded, diagnostics
+	}
+
+	// within_cidr restricts the block of its own IP version
+	prefixLengthAttribute, prefixLength := "prefix_length", data.PrefixLength
+	if isIPv6CIDR(within) {
+		prefixLengthAttribute, prefixLength = "ipv6_prefix_length", data.Ipv6PrefixLength
+	}
+	if prefixLength.IsNull() {
+		diagnostics.AddAttributeError(
+			path.Root("within_cidr"),
+			"Range Without Block",
+			fmt.Sprintf("within_cidr %s restricts the block sized by %s, which is not set", within, prefixLengthAttribute),
+		)
+		return within, excluded, diagnostics
+	}
+
+	_, withinNet, _ := net.ParseCIDR(within)
+	if !prefixLength.IsUnknown() && int(prefixLength.ValueInt64()) < getNetworkPrefixLength(withinNet) {
+		diagnostics.AddAttributeError(
+			path.Root(prefixLengthAttribute),
+			"Prefix Length Too Small",
+			fmt.Sprintf("A /%d block doesn't fit within_cidr %s", prefixLength.ValueInt64(), within),
+		)
+	}
+
+	return within, excluded, diagnostics
+}
+
+// validateCidrAttribute checks a range set in an attribute, rejecting host bits so the range is unambiguous
+func validateCidrAttribute(attributePath path.Path, value string) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	_, block, err := net.ParseCIDR(value)
+	if err != nil {
+		diagnostics.AddAttributeError(
+			attributePath,
+			"Invalid CIDR",
+			fmt.Sprintf("%s must be in CIDR notation: %s", attributePath, err),
+		)
+	} else if block.String() != value {
+		diagnostics.AddAttributeError(
+			attributePath,
+			"Invalid CIDR",
+			fmt.Sprintf("%s %s has host bits set, did you mean %s?", attributePath, value, block),
+		)
+	}
+	return diagnostics
+}
+
 func getNetworkPrefixLength(network *net.IPNet) int {
 	ones, _ := network.Mask.Size()
 	return ones
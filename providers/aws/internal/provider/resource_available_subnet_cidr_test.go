@@ -2,36 +2,350 @@
 package provider
 
 import (
+	"context"
+	"fmt"
+	"regexp"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	frameworkTypes "github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAvailableSubnetCidrResource_Basic(t *testing.T) {
-	t.Skip("This test requires actual AWS credentials and a VPC to test against")
+	fake := newFakeEC2(t)
+	vpcID := fake.addVpc("10.0.0.0/16")
+	fake.addSubnet(vpcID, "10.0.0.0/24")
+	appsID := fake.addSubnet(vpcID, "10.0.1.0/24")
+	fake.addSubnet(vpcID, "10.0.3.0/24")
 
-	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+	config := fmt.Sprintf(`
+		provider "dx" {}
+
+		resource "dx_available_subnet_cidr" "test" {
+			vpc_id        = %q
+			prefix_length = 24
+		}
+	`, vpcID)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(),
 		Steps: []resource.TestStep{
 			{
-				Config: `
-					provider "dx" {}
-
-					data "aws_vpc" "test" {
-						default = true
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "id", vpcID+"/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.0.2.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "prefix_length", "24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "status", cidrStatusOK),
+				),
+			},
+			{
+				// A subnet created outside Terraform takes part of the block
+				PreConfig: func() { fake.addSubnet(vpcID, "10.0.2.128/25") },
+				Config:    config,
+				Check:     resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "status", cidrStatusOverlapping),
+			},
+			{
+				ResourceName:  "dx_available_subnet_cidr.test",
+				ImportState:   true,
+				ImportStateId: appsID,
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 imported resource, got %d", len(states))
+					}
+					if cidrBlock := states[0].Attributes["cidr_block"]; cidrBlock != "10.0.1.0/24" {
+						return fmt.Errorf("expected cidr_block 10.0.1.0/24, got %s", cidrBlock)
 					}
+					if vpc := states[0].Attributes["vpc_id"]; vpc != vpcID {
+						return fmt.Errorf("expected vpc_id %s, got %s", vpcID, vpc)
+					}
+					return nil
+				},
+			},
+			{
+				ResourceName:  "dx_available_subnet_cidr.test",
+				ImportState:   true,
+				ImportStateId: "subnet-0123456789abcdef0",
+				ExpectError:   regexp.MustCompile(`Subnet with ID subnet-0123456789abcdef0 not found`),
+			},
+			{
+				// The block is dropped from state with the VPC, so it is planned again
+				PreConfig:          func() { fake.deleteVpc(vpcID) },
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestVpcFreeSpaceDataSource_Basic(t *testing.T) {
+	fake := newFakeEC2(t)
+	vpcID := fake.addVpc("10.0.0.0/22")
+	fake.addSubnet(vpcID, "10.0.0.0/24")
+	fake.addSubnet(vpcID, "10.0.2.0/24")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "dx" {}
 
-					resource "dx_available_subnet_cidr" "test" {
-						vpc_id        = data.aws_vpc.test.id
-						prefix_length = 24
+					data "dx_vpc_free_space" "test" {
+						vpc_id         = %q
+						prefix_lengths = [24, 25]
 					}
-				`,
+				`, vpcID),
 				Check: resource.ComposeTestCheckFunc(
-					resource.TestCheckResourceAttrSet("dx_available_subnet_cidr.test", "id"),
-					resource.TestCheckResourceAttrSet("dx_available_subnet_cidr.test", "cidr_block"),
-					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "prefix_length", "24"),
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "free_cidrs.#", "2"),
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "free_cidrs.0", "10.0.1.0/24"),
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "free_cidrs.1", "10.0.3.0/24"),
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "largest_available_prefix_length", "24"),
+					resource.TestCheckResourceAttr("data.dx_vpc_free_space.test", "available_blocks.25", "4"),
 				),
 			},
 		},
 	})
 }
+
+func TestCidrBlockStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		block    string
+		vpcCIDRs []string
+		subnets  []subnetLayout
+		expected string
+	}{
+		{
+			name:     "free block",
+			block:    "10.0.1.0/24",
+			vpcCIDRs: []string{"10.0.0.0/16"},
+			subnets:  []subnetLayout{{id: "subnet-other", cidrBlock: "10.0.0.0/24"}},
+			expected: cidrStatusOK,
+		},
+		{
+			name:     "block used by its own subnet",
+			block:    "10.0.1.0/24",
+			vpcCIDRs: []string{"10.0.0.0/16"},
+			subnets:  []subnetLayout{{id: "subnet-ours", cidrBlock: "10.0.1.0/24"}},
+			expected: cidrStatusOK,
+		},
+		{
+			name:     "block overlapping another subnet",
+			block:    "10.0.1.0/24",
+			vpcCIDRs: []string{"10.0.0.0/16"},
+			subnets:  []subnetLayout{{id: "subnet-other", cidrBlock: "10.0.0.0/23"}},
+			expected: cidrStatusOverlapping,
+		},
+		{
+			name:     "IPv6 block overlapping another subnet",
+			block:    "2600:1f18:abcd:1200::/64",
+			vpcCIDRs: []string{"10.0.0.0/16", "2600:1f18:abcd:1200::/56"},
+			subnets:  []subnetLayout{{id: "subnet-other", cidrBlock: "10.0.0.0/24", ipv6CidrBlocks: []string{"2600:1f18:abcd:1200::/60"}}},
+			expected: cidrStatusOverlapping,
+		},
+		{
+			name:     "secondary CIDR block disassociated",
+			block:    "10.1.1.0/24",
+			vpcCIDRs: []string{"10.0.0.0/16"},
+			expected: cidrStatusOutsideAddressSpace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, detail := cidrBlockStatus(tt.block, tt.vpcCIDRs, tt.subnets)
+			if status != tt.expected {
+				t.Errorf("expected status %s, got %s (%s)", tt.expected, status, detail)
+			}
+		})
+	}
+}
+
+func TestCidrWithinVpc(t *testing.T) {
+	t.Parallel()
+
+	vpcCIDRs := []string{"10.0.0.0/16", "100.64.0.0/24"}
+
+	for within, expected := range map[string]bool{
+		"10.0.16.0/20":   true,
+		"10.0.0.0/16":    true,
+		"100.64.0.0/25":  true,
+		"10.0.0.0/15":    false,
+		"10.1.0.0/20":    false,
+		"100.64.1.0/24":  false,
+		"not-a-cidr/100": false,
+	} {
+		if got := cidrWithinVpc(within, vpcCIDRs); got != expected {
+			t.Errorf("%s: expected %t, got %t", within, expected, got)
+		}
+	}
+}
+
+func TestAvailableSubnetCidrResource_InvalidRanges(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		attributes    string
+		expectedError string
+	}{
+		{"invalid within_cidr", `within_cidr = "10.0.0.0"`, `within_cidr must be in CIDR notation`},
+		{"within_cidr with host bits", `within_cidr = "10.0.0.1/24"`, `did you mean 10.0.0.0/24\?`},
+		{"prefix larger than within_cidr", `within_cidr = "10.0.0.0/25"`, `A /24 block doesn't fit within_cidr`},
+		{"invalid exclude_cidrs", `exclude_cidrs = ["10.0.0.0/24", "192.168.0.0"]`, `exclude_cidrs must be in CIDR notation`},
+		{"exclude_cidrs with host bits", `exclude_cidrs = ["192.168.0.1/16"]`, `did you mean 192.168.0.0/16\?`},
+		{"IPv6 within_cidr without ipv6_prefix_length", `within_cidr = "2600:1f18:abcd:1200::/56"`, `restricts the block sized by[\s\n]+ipv6_prefix_length`},
+		{"IPv6 prefix length not a multiple of 4", `ipv6_prefix_length = 50`, `ipv6_prefix_length value must be one of`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  vpc_id        = "vpc-0123456789abcdef0"
+  prefix_length = 24
+  %s
+}
+`, tt.attributes),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(tt.expectedError),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestSelectAvailableCidrBlock(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		vpcCIDRs     []string
+		existing     []string
+		excluded     []string
+		within       string
+		prefixLength int
+		expected     string
+	}{
+		{"first free block", []string{"10.0.0.0/16"}, []string{"10.0.0.0/24"}, nil, "", 24, "10.0.1.0/24"},
+		{"excluded block", []string{"10.0.0.0/16"}, []string{"10.0.0.0/24"}, []string{"10.0.1.0/24"}, "", 24, "10.0.2.0/24"},
+		{"within", []string{"10.0.0.0/16"}, nil, nil, "10.0.16.0/20", 24, "10.0.16.0/24"},
+		{"IPv6", []string{"2600:1f18:abcd:1200::/56"}, []string{"2600:1f18:abcd:1200::/64"}, nil, "", 64, "2600:1f18:abcd:1201::/64"},
+		{"large IPv6 range", []string{"2600:1f18:abc0::/44"}, []string{"2600:1f18:abc0::/64"}, nil, "", 64, "2600:1f18:abc0:1::/64"},
+		{"IPv6 ignores an IPv4 within", []string{"2600:1f18:abcd:1200::/56"}, nil, nil, "10.0.16.0/20", 64, "2600:1f18:abcd:1200::/64"},
+		{"no free block", []string{"10.0.0.0/24"}, []string{"10.0.0.0/25", "10.0.0.128/25"}, nil, "", 25, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := selectAvailableCidrBlock(context.Background(), "vpc-0123456789abcdef0", tt.vpcCIDRs, tt.existing, tt.excluded, tt.within, tt.prefixLength)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q (%v)", tt.expected, got, diags)
+			}
+			if tt.expected == "" && !diags.HasError() {
+				t.Error("expected an error when no block is available")
+			}
+		})
+	}
+}
+
+func TestAvailableSubnetCidrResource_MissingPrefixLength(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "dx_available_subnet_cidr" "test" {
+  vpc_id = "vpc-0123456789abcdef0"
+}
+`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`At least one of prefix_length and ipv6_prefix_length must be set`),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_InvalidImportID(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "dx_available_subnet_cidr" "test" {
+  vpc_id        = "vpc-0123456789abcdef0"
+  prefix_length = 24
+}
+`,
+				ResourceName:  "dx_available_subnet_cidr.test",
+				ImportState:   true,
+				ImportStateId: "vpc-0123456789abcdef0",
+				ExpectError:   regexp.MustCompile(`The import ID must be an AWS subnet ID in the format subnet-xxxxxxxxx`),
+			},
+		},
+	})
+}
+
+func TestNewSubnetLayout(t *testing.T) {
+	t.Parallel()
+
+	layout := newSubnetLayout(types.Subnet{
+		SubnetId:  aws.String("subnet-0123456789abcdef0"),
+		CidrBlock: aws.String("10.0.1.0/24"),
+		Ipv6CidrBlockAssociationSet: []types.SubnetIpv6CidrBlockAssociation{
+			{
+				Ipv6CidrBlock:      aws.String("2600:1f18:abcd:1200::/64"),
+				Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeAssociated},
+			},
+			{
+				Ipv6CidrBlock:      aws.String("2600:1f18:abcd:1201::/64"),
+				Ipv6CidrBlockState: &types.SubnetCidrBlockState{State: types.SubnetCidrBlockStateCodeDisassociated},
+			},
+		},
+	})
+
+	if layout.id != "subnet-0123456789abcdef0" || layout.cidrBlock != "10.0.1.0/24" {
+		t.Errorf("unexpected subnet layout %+v", layout)
+	}
+	if len(layout.ipv6CidrBlocks) != 1 || layout.ipv6CidrBlocks[0] != "2600:1f18:abcd:1200::/64" {
+		t.Errorf("expected only the associated IPv6 block, got %v", layout.ipv6CidrBlocks)
+	}
+}
+
+func TestAvailableSubnetCidrID(t *testing.T) {
+	t.Parallel()
+
+	dualStack := availableSubnetCidrResourceModel{
+		VpcID:            frameworkTypes.StringValue("vpc-0123456789abcdef0"),
+		PrefixLength:     frameworkTypes.Int64Value(24),
+		Ipv6PrefixLength: frameworkTypes.Int64Value(64),
+	}
+	if got := availableSubnetCidrID(dualStack); got != "vpc-0123456789abcdef0/24" {
+		t.Errorf("unexpected ID %q", got)
+	}
+
+	ipv6Only := availableSubnetCidrResourceModel{
+		VpcID:            frameworkTypes.StringValue("vpc-0123456789abcdef0"),
+		PrefixLength:     frameworkTypes.Int64Null(),
+		Ipv6PrefixLength: frameworkTypes.Int64Value(64),
+	}
+	if got := availableSubnetCidrID(ipv6Only); got != "vpc-0123456789abcdef0/ipv6/64" {
+		t.Errorf("unexpected ID %q", got)
+	}
+}
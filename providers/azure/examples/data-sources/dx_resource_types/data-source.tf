@@ -0,0 +1,8 @@
+data "dx_resource_types" "all" {}
+
+# Resource types grouped by category
+output "resource_types_by_category" {
+  value = {
+    for t in data.dx_resource_types.all.resource_types : t.category => t.resource_type...
+  }
+}
@@ -0,0 +1,8 @@
+data "dx_virtual_network_free_space" "this" {
+  virtual_network_id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/dx-d-itn-network-rg-01/providers/Microsoft.Network/virtualNetworks/dx-d-itn-common-vnet-01"
+  prefix_lengths     = [24, 27]
+}
+
+output "free_24_blocks" {
+  value = data.dx_virtual_network_free_space.this.available_blocks["24"]
+}
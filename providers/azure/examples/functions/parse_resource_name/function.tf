@@ -0,0 +1,4 @@
+# Decomposes a dx resource name into its parts
+output "parsed_resource_name" {
+  value = provider::dx::parse_resource_name("dx-d-itn-test-example-snet-01")
+}
@@ -0,0 +1,12 @@
+# Generates the names of several resources sharing the same configuration.
+# NOTE: resource_types also accepts a map of resource type to instance number
+output "resource_names" {
+  value = provider::dx::resource_names({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    domain          = "test",
+    name            = "web",
+    instance_number = 1,
+  }, ["app_service", "app_service_plan", "storage_account"])
+}
@@ -1,6 +1,6 @@
 provider "dx" {
   prefix      = "<project_prefix>" # e.g., "dx", "io" (Optional)
   environment = "<environment>"    # d, u, or p (dev, uat, or prod) (Optional)
-  location    = "<location>"       # itn (Italy North) or weu (West Europe) (Optional)
+  location    = "<location>"       # short or long format, e.g. itn/italynorth or swc/swedencentral (Optional)
   domain      = "<domain>"         # e.g., "test" (Optional)
 }
\ No newline at end of file
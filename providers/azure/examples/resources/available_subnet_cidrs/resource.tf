@@ -0,0 +1,7 @@
+resource "dx_available_subnet_cidrs" "app" {
+  virtual_network_id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/dx-d-itn-network-rg-01/providers/Microsoft.Network/virtualNetworks/dx-d-itn-common-vnet-01"
+  prefix_lengths = {
+    apps = 24
+    pep  = 27
+  }
+}
@@ -0,0 +1,242 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Values of the azure_environment attribute, named after the ARM_ENVIRONMENT values of azurerm
+const (
+	azureEnvironmentPublic       = "public"
+	azureEnvironmentUSGovernment = "usgovernment"
+	azureEnvironmentChina        = "china"
+)
+
+var azureClouds = map[string]cloud.Configuration{
+	azureEnvironmentPublic:       cloud.AzurePublic,
+	azureEnvironmentUSGovernment: cloud.AzureGovernment,
+	azureEnvironmentChina:        cloud.AzureChina,
+}
+
+// networkClient reads the Virtual Networks and subnets the allocators work on, and writes the
+// tags holding reservations. Errors wrap the Azure responses, so isAzureNotFound applies.
+type networkClient interface {
+	getVirtualNetwork(ctx context.Context, parsedID *parsedVNetID) (*armnetwork.VirtualNetwork, error)
+	listSubnets(ctx context.Context, parsedID *parsedVNetID) ([]*armnetwork.Subnet, error)
+	getSubnet(ctx context.Context, subnetInfo *parsedSubnetID) (*armnetwork.Subnet, error)
+	updateVirtualNetworkTags(ctx context.Context, parsedID *parsedVNetID, etag string, tags map[string]*string) error
+}
+
+var _ networkClient = &azureClients{}
+
+// azureClients implements networkClient with the Azure SDK, all clients sharing the same credential and cloud
+type azureClients struct {
+	credential azcore.TokenCredential
+	options    *arm.ClientOptions
+}
+
+func (c *azureClients) getVirtualNetwork(ctx context.Context, parsedID *parsedVNetID) (*armnetwork.VirtualNetwork, error) {
+	client, err := armnetwork.NewVirtualNetworksClient(parsedID.subscriptionID, c.credential, c.options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Azure VirtualNetworks client: %w", err)
+	}
+
+	vnet, err := client.Get(ctx, parsedID.resourceGroupName, parsedID.vnetName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Virtual Network '%s': %w", parsedID.vnetName, err)
+	}
+	return &vnet.VirtualNetwork, nil
+}
+
+func (c *azureClients) listSubnets(ctx context.Context, parsedID *parsedVNetID) ([]*armnetwork.Subnet, error) {
+	client, err := armnetwork.NewSubnetsClient(parsedID.subscriptionID, c.credential, c.options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Azure Subnets client: %w", err)
+	}
+
+	var subnets []*armnetwork.Subnet
+	pager := client.NewListPager(parsedID.resourceGroupName, parsedID.vnetName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list subnets for VNet '%s': %w", parsedID.vnetName, err)
+		}
+		subnets = append(subnets, page.Value...)
+	}
+	return subnets, nil
+}
+
+func (c *azureClients) getSubnet(ctx context.Context, subnetInfo *parsedSubnetID) (*armnetwork.Subnet, error) {
+	client, err := armnetwork.NewSubnetsClient(subnetInfo.subscriptionID, c.credential, c.options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Azure Subnets client: %w", err)
+	}
+
+	subnet, err := client.Get(ctx, subnetInfo.resourceGroupName, subnetInfo.vnetName, subnetInfo.subnetName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subnet '%s' in VNet '%s': %w", subnetInfo.subnetName, subnetInfo.vnetName, err)
+	}
+	return &subnet.Subnet, nil
+}
+
+// updateVirtualNetworkTags replaces the tags of a VNet, leaving the rest of it untouched. The PATCH
+// is conditional on the ETag of the VNet read, so it fails with 412 Precondition Failed rather
+// than overwriting a change made in the meantime, e.g. a tag added by another run.
+func (c *azureClients) updateVirtualNetworkTags(ctx context.Context, parsedID *parsedVNetID, etag string, tags map[string]*string) error {
+	client, err := armnetwork.NewVirtualNetworksClient(parsedID.subscriptionID, c.credential, c.options)
+	if err != nil {
+		return fmt.Errorf("unable to create Azure VirtualNetworks client: %w", err)
+	}
+
+	conditional := policy.WithHTTPHeader(ctx, http.Header{"If-Match": []string{etag}})
+	if _, err := client.UpdateTags(conditional, parsedID.resourceGroupName, parsedID.vnetName, armnetwork.TagsObject{Tags: tags}, nil); err != nil {
+		return fmt.Errorf("updating tags of Virtual Network '%s': %w", parsedID.vnetName, err)
+	}
+	return nil
+}
+
+// azureCloudConfiguration returns the cloud of the azure_environment attribute, falling back to
+// ARM_ENVIRONMENT, with the Resource Manager endpoint replaced by arm_endpoint when set
+func azureCloudConfiguration(environment, armEndpoint types.String) (cloud.Configuration, error) {
+	name := stringOrEnv(environment, "ARM_ENVIRONMENT")
+	if name == "" {
+		name = azureEnvironmentPublic
+	}
+	base, ok := azureClouds[name]
+	if !ok {
+		return cloud.Configuration{}, fmt.Errorf("unknown Azure environment %q, expected one of %s, %s or %s", name, azureEnvironmentPublic, azureEnvironmentUSGovernment, azureEnvironmentChina)
+	}
+
+	if armEndpoint.IsNull() || armEndpoint.IsUnknown() {
+		return base, nil
+	}
+
+	endpoint, err := url.Parse(armEndpoint.ValueString())
+	if err != nil || endpoint.Host == "" {
+		return cloud.Configuration{}, fmt.Errorf("arm_endpoint %q is not a valid URL", armEndpoint.ValueString())
+	}
+	if endpoint.Scheme != "https" && !(endpoint.Scheme == "http" && isLoopbackHost(endpoint.Hostname())) {
+		return cloud.Configuration{}, fmt.Errorf("arm_endpoint %q must use https, http is only allowed on the local machine", armEndpoint.ValueString())
+	}
+
+	// The clouds are shared by every SDK client of the process, so the services are copied
+	custom := cloud.Configuration{
+		ActiveDirectoryAuthorityHost: base.ActiveDirectoryAuthorityHost,
+		Services:                     make(map[cloud.ServiceName]cloud.ServiceConfiguration, len(base.Services)),
+	}
+	for name, service := range base.Services {
+		custom.Services[name] = service
+	}
+	resourceManager := custom.Services[cloud.ResourceManager]
+	resourceManager.Endpoint = endpoint.String()
+	custom.Services[cloud.ResourceManager] = resourceManager
+	return custom, nil
+}
+
+// retryConfig is the retry policy of the max_retries and max_retry_delay attributes, unset values
+// keeping the SDK defaults
+type retryConfig struct {
+	maxRetries    *int64
+	maxRetryDelay time.Duration
+}
+
+// newRetryConfig reads the retry attributes of the provider
+func newRetryConfig(maxRetries types.Int64, maxRetryDelay types.String) (retryConfig, error) {
+	var retry retryConfig
+	if !maxRetries.IsNull() && !maxRetries.IsUnknown() {
+		value := maxRetries.ValueInt64()
+		retry.maxRetries = &value
+	}
+	if !maxRetryDelay.IsNull() && !maxRetryDelay.IsUnknown() {
+		delay, err := time.ParseDuration(maxRetryDelay.ValueString())
+		if err != nil || delay <= 0 {
+			return retry, fmt.Errorf("max_retry_delay %q must be a positive duration, e.g. 30s or 2m", maxRetryDelay.ValueString())
+		}
+		retry.maxRetryDelay = delay
+	}
+	return retry, nil
+}
+
+// armClientOptions returns the options of the ARM clients. Tokens are only sent over http to a
+// local stand-in of Resource Manager, as allowed by azureCloudConfiguration.
+//
+// Throttled requests (429) are retried by the SDK, waiting for the Retry-After of the response
+// when set: a Retry-After longer than the maximum delay fails the request rather than waiting.
+func armClientOptions(cloudConfig cloud.Configuration, retry retryConfig) *arm.ClientOptions {
+	options := &arm.ClientOptions{}
+	options.Cloud = cloudConfig
+	if endpoint, err := url.Parse(cloudConfig.Services[cloud.ResourceManager].Endpoint); err == nil && endpoint.Scheme == "http" {
+		options.InsecureAllowCredentialWithHTTP = true
+	}
+
+	if retry.maxRetries != nil {
+		// The SDK reads zero as its default, no retries is a negative value
+		options.Retry.MaxRetries = int32(*retry.maxRetries)
+		if *retry.maxRetries == 0 {
+			options.Retry.MaxRetries = -1
+		}
+	}
+	options.Retry.MaxRetryDelay = retry.maxRetryDelay
+	return options
+}
+
+// azureAPIErrorDiagnostic describes a failed Azure call, telling throttling, missing permissions
+// and missing resources apart so operators know what to fix
+func azureAPIErrorDiagnostic(err error) diag.Diagnostic {
+	var respErr *azcore.ResponseError
+	var authErr *azidentity.AuthenticationFailedError
+	statusCode := 0
+	if errors.As(err, &respErr) {
+		statusCode = respErr.StatusCode
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return diag.NewErrorDiagnostic(
+			"Azure API Throttled",
+			fmt.Sprintf("%s\n\nAzure Resource Manager kept throttling the requests after the retries of the provider. "+
+				"Lower the Terraform parallelism, or raise max_retries and max_retry_delay in the provider configuration.", err),
+		)
+	case statusCode == http.StatusForbidden:
+		return diag.NewErrorDiagnostic(
+			"Azure Authorization Failed",
+			fmt.Sprintf("%s\n\nThe identity of the provider needs the Microsoft.Network/virtualNetworks/read and "+
+				"Microsoft.Network/virtualNetworks/subnets/read permissions on the Virtual Network, e.g. from the Reader role. "+
+				"The virtual_network_tags reservation backend also needs Microsoft.Network/virtualNetworks/write, used only to update the tags.", err),
+		)
+	case statusCode == http.StatusNotFound:
+		return diag.NewErrorDiagnostic(
+			"Azure Resource Not Found",
+			fmt.Sprintf("%s\n\nCheck the Virtual Network ID, and that the Virtual Network exists in a subscription the provider can read.", err),
+		)
+	case statusCode == http.StatusUnauthorized || errors.As(err, &authErr):
+		return diag.NewErrorDiagnostic(
+			"Azure Authentication Failed",
+			fmt.Sprintf("%s\n\nCheck the authentication attributes of the provider, e.g. use_cli, use_msi or use_oidc.", err),
+		)
+	default:
+		return diag.NewErrorDiagnostic("Azure API Error", err.Error())
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
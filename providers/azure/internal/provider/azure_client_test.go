@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestAzureCloudConfiguration(t *testing.T) {
+	tests := []struct {
+		name                string
+		environment         types.String
+		armEndpoint         types.String
+		env                 string
+		expectedAuthority   string
+		expectedARMEndpoint string
+		expectedError       string
+	}{
+		{
+			name:                "default",
+			expectedAuthority:   cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+			expectedARMEndpoint: cloud.AzurePublic.Services[cloud.ResourceManager].Endpoint,
+		},
+		{
+			name:                "attribute",
+			environment:         types.StringValue(azureEnvironmentUSGovernment),
+			env:                 azureEnvironmentChina,
+			expectedAuthority:   cloud.AzureGovernment.ActiveDirectoryAuthorityHost,
+			expectedARMEndpoint: cloud.AzureGovernment.Services[cloud.ResourceManager].Endpoint,
+		},
+		{
+			name:                "environment variable",
+			env:                 azureEnvironmentChina,
+			expectedAuthority:   cloud.AzureChina.ActiveDirectoryAuthorityHost,
+			expectedARMEndpoint: cloud.AzureChina.Services[cloud.ResourceManager].Endpoint,
+		},
+		{
+			name:                "custom endpoint",
+			armEndpoint:         types.StringValue("https://management.private.example.com"),
+			expectedAuthority:   cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+			expectedARMEndpoint: "https://management.private.example.com",
+		},
+		{
+			name:                "local stand-in",
+			armEndpoint:         types.StringValue("http://127.0.0.1:8080"),
+			expectedAuthority:   cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+			expectedARMEndpoint: "http://127.0.0.1:8080",
+		},
+		{
+			name:          "unknown environment",
+			env:           "germany",
+			expectedError: "unknown Azure environment",
+		},
+		{
+			name:          "remote http endpoint",
+			armEndpoint:   types.StringValue("http://management.private.example.com"),
+			expectedError: "must use https",
+		},
+		{
+			name:          "invalid endpoint",
+			armEndpoint:   types.StringValue("management.private.example.com"),
+			expectedError: "is not a valid URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ARM_ENVIRONMENT", tt.env)
+			cloudConfig, err := azureCloudConfiguration(tt.environment, tt.armEndpoint)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("expected an error containing %q, got %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if cloudConfig.ActiveDirectoryAuthorityHost != tt.expectedAuthority {
+				t.Errorf("expected authority %s, got %s", tt.expectedAuthority, cloudConfig.ActiveDirectoryAuthorityHost)
+			}
+			if endpoint := cloudConfig.Services[cloud.ResourceManager].Endpoint; endpoint != tt.expectedARMEndpoint {
+				t.Errorf("expected Resource Manager endpoint %s, got %s", tt.expectedARMEndpoint, endpoint)
+			}
+		})
+	}
+
+	// Custom endpoints must not leak into the clouds shared by the process
+	if endpoint := cloud.AzurePublic.Services[cloud.ResourceManager].Endpoint; endpoint != "https://management.azure.com" {
+		t.Errorf("the public cloud was modified, its Resource Manager endpoint is %s", endpoint)
+	}
+}
+
+func TestArmClientOptions(t *testing.T) {
+	t.Setenv("ARM_ENVIRONMENT", "")
+
+	local, err := azureCloudConfiguration(types.StringNull(), types.StringValue("http://localhost:8080"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !armClientOptions(local, retryConfig{}).InsecureAllowCredentialWithHTTP {
+		t.Error("expected tokens to be allowed over http to a local endpoint")
+	}
+	if armClientOptions(cloud.AzurePublic, retryConfig{}).InsecureAllowCredentialWithHTTP {
+		t.Error("expected tokens to be sent over https only")
+	}
+}
+
+func TestNewRetryConfig(t *testing.T) {
+	retry, err := newRetryConfig(types.Int64Value(0), types.StringValue("2m"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if retry.maxRetryDelay != 2*time.Minute {
+		t.Errorf("expected a 2m delay, got %s", retry.maxRetryDelay)
+	}
+	if options := armClientOptions(cloud.AzurePublic, retry); options.Retry.MaxRetries != -1 || options.Retry.MaxRetryDelay != 2*time.Minute {
+		t.Errorf("expected no retries and a 2m delay, got %d and %s", options.Retry.MaxRetries, options.Retry.MaxRetryDelay)
+	}
+
+	if options := armClientOptions(cloud.AzurePublic, retryConfig{}); options.Retry.MaxRetries != 0 || options.Retry.MaxRetryDelay != 0 {
+		t.Errorf("expected the SDK defaults, got %d and %s", options.Retry.MaxRetries, options.Retry.MaxRetryDelay)
+	}
+
+	for _, delay := range []string{"30", "-1s", "0s"} {
+		if _, err := newRetryConfig(types.Int64Null(), types.StringValue(delay)); err == nil {
+			t.Errorf("expected an error for max_retry_delay %q", delay)
+		}
+	}
+}
+
+func TestAzureClientsRetry(t *testing.T) {
+	maxRetries := int64(2)
+
+	t.Run("throttled then served", func(t *testing.T) {
+		fake := newFakeARM(t)
+		parsedID, _ := parseVNetID(fake.addVirtualNetwork("vnet", "10.0.0.0/16"))
+		fake.throttle(2, "retry-after-ms", "10")
+
+		if _, err := fake.networkWithRetry(t, retryConfig{maxRetries: &maxRetries}).getVirtualNetwork(t.Context(), parsedID); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if requests := fake.requestCount(); requests != 3 {
+			t.Errorf("expected 3 requests, got %d", requests)
+		}
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		fake := newFakeARM(t)
+		parsedID, _ := parseVNetID(fake.addVirtualNetwork("vnet", "10.0.0.0/16"))
+		fake.throttle(3, "retry-after-ms", "10")
+
+		_, err := fake.networkWithRetry(t, retryConfig{maxRetries: &maxRetries}).getVirtualNetwork(t.Context(), parsedID)
+		if summary := azureAPIErrorDiagnostic(err).Summary(); summary != "Azure API Throttled" {
+			t.Errorf("expected a throttling diagnostic, got %q for %v", summary, err)
+		}
+	})
+
+	t.Run("Retry-After beyond the maximum delay", func(t *testing.T) {
+		fake := newFakeARM(t)
+		parsedID, _ := parseVNetID(fake.addVirtualNetwork("vnet", "10.0.0.0/16"))
+		fake.throttle(1, "Retry-After", "120")
+
+		_, err := fake.networkWithRetry(t, retryConfig{maxRetries: &maxRetries, maxRetryDelay: time.Second}).getVirtualNetwork(t.Context(), parsedID)
+		if err == nil {
+			t.Fatal("expected the throttled request to fail")
+		}
+		if requests := fake.requestCount(); requests != 1 {
+			t.Errorf("expected no retry, got %d requests", requests)
+		}
+	})
+}
+
+func TestAzureAPIErrorDiagnostic(t *testing.T) {
+	for statusCode, expected := range map[int]string{
+		http.StatusTooManyRequests:     "Azure API Throttled",
+		http.StatusForbidden:           "Azure Authorization Failed",
+		http.StatusNotFound:            "Azure Resource Not Found",
+		http.StatusUnauthorized:        "Azure Authentication Failed",
+		http.StatusInternalServerError: "Azure API Error",
+	} {
+		err := fmt.Errorf("failed to get Virtual Network 'vnet': %w", &azcore.ResponseError{StatusCode: statusCode})
+		if summary := azureAPIErrorDiagnostic(err).Summary(); summary != expected {
+			t.Errorf("%d: expected %q, got %q", statusCode, expected, summary)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// oidcTokenAudience is the audience of the ID tokens exchanged for Azure tokens
+const oidcTokenAudience = "api://AzureADTokenExchange"
+
+// azureAuthConfig selects how the provider authenticates to Azure. Unset attributes fall back to
+// the ARM_* environment variables used by azurerm.
+type azureAuthConfig struct {
+	useCLI  bool
+	useMSI  bool
+	useOIDC bool
+
+	clientID          string
+	tenantID          string
+	oidcTokenFilePath string
+	// oidcRequestURL and oidcRequestToken fetch ID tokens from GitHub Actions when no token file is set
+	oidcRequestURL   string
+	oidcRequestToken string
+
+	// explicit is false when nothing is configured, keeping the Azure CLI then DefaultAzureCredential chain
+	explicit bool
+}
+
+// newAzureAuthConfig reads the authentication attributes of the provider, falling back to the environment
+func newAzureAuthConfig(config dxProviderModel) (azureAuthConfig, error) {
+	var auth azureAuthConfig
+	var err error
+
+	bools := []struct {
+		value  types.Bool
+		envVar string
+		target *bool
+		preset bool
+	}{
+		{config.UseCLI, "ARM_USE_CLI", &auth.useCLI, true},
+		{config.UseMSI, "ARM_USE_MSI", &auth.useMSI, false},
+		{config.UseOIDC, "ARM_USE_OIDC", &auth.useOIDC, false},
+	}
+	for _, b := range bools {
+		*b.target = b.preset
+		switch {
+		case !b.value.IsNull() && !b.value.IsUnknown():
+			*b.target, auth.explicit = b.value.ValueBool(), true
+		case os.Getenv(b.envVar) != "":
+			if *b.target, err = strconv.ParseBool(os.Getenv(b.envVar)); err != nil {
+				return auth, fmt.Errorf("invalid %s value %q: %w", b.envVar, os.Getenv(b.envVar), err)
+			}
+			auth.explicit = true
+		}
+	}
+
+	auth.clientID = stringOrEnv(config.ClientID, "ARM_CLIENT_ID")
+	auth.tenantID = stringOrEnv(config.TenantID, "ARM_TENANT_ID")
+	auth.oidcTokenFilePath = stringOrEnv(config.OIDCTokenFilePath, "ARM_OIDC_TOKEN_FILE_PATH")
+	auth.oidcRequestURL = firstEnv("ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL")
+	auth.oidcRequestToken = firstEnv("ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if auth.clientID != "" || auth.tenantID != "" || auth.oidcTokenFilePath != "" {
+		auth.explicit = true
+	}
+
+	return auth, nil
+}
+
+// newAzureCredential builds the credential shared by every resource and data source of the provider,
+// authenticating against the Microsoft Entra authority of the cloud. The enabled methods are tried in
+// order: OIDC, managed identity, then Azure CLI.
+func newAzureCredential(auth azureAuthConfig, cloudConfig cloud.Configuration) (azcore.TokenCredential, error) {
+	clientOptions := azcore.ClientOptions{Cloud: cloudConfig}
+	if !auth.explicit {
+		return defaultAzureCredential(clientOptions)
+	}
+
+	var sources []azcore.TokenCredential
+	if auth.useOIDC {
+		cred, err := oidcCredential(auth, clientOptions)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, cred)
+	}
+	if auth.useMSI {
+		options := &azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOptions}
+		if auth.clientID != "" {
+			options.ID = azidentity.ClientID(auth.clientID)
+		}
+		cred, err := azidentity.NewManagedIdentityCredential(options)
+		if err != nil {
+			return nil, fmt.Errorf("managed identity: %w", err)
+		}
+		sources = append(sources, cred)
+	}
+	if auth.useCLI {
+		cred, err := azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{TenantID: auth.tenantID})
+		if err != nil {
+			return nil, fmt.Errorf("Azure CLI: %w", err)
+		}
+		sources = append(sources, cred)
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no authentication method enabled, set at least one of use_cli, use_msi and use_oidc")
+	}
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+	return azidentity.NewChainedTokenCredential(sources, nil)
+}
+
+// defaultAzureCredential prefers Azure CLI, falling back to DefaultAzureCredential
+func defaultAzureCredential(clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	cliCred, err := azidentity.NewAzureCLICredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("Azure CLI: %w", err)
+	}
+	defaultCred, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOptions})
+	if err != nil {
+		return nil, fmt.Errorf("DefaultAzureCredential: %w", err)
+	}
+	return azidentity.NewChainedTokenCredential([]azcore.TokenCredential{cliCred, defaultCred}, nil)
+}
+
+// oidcCredential exchanges an ID token for Azure tokens, reading it from a file (e.g. Kubernetes
+// workload identity) or requesting it to GitHub Actions
+func oidcCredential(auth azureAuthConfig, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	if auth.clientID == "" || auth.tenantID == "" {
+		return nil, errors.New("OIDC authentication requires client_id and tenant_id")
+	}
+
+	if auth.oidcTokenFilePath != "" {
+		cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: clientOptions,
+			ClientID:      auth.clientID,
+			TenantID:      auth.tenantID,
+			TokenFilePath: auth.oidcTokenFilePath,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("OIDC: %w", err)
+		}
+		return cred, nil
+	}
+
+	if auth.oidcRequestURL == "" || auth.oidcRequestToken == "" {
+		return nil, errors.New("OIDC authentication requires oidc_token_file_path, or the ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN variables set by GitHub Actions")
+	}
+	cred, err := azidentity.NewClientAssertionCredential(auth.tenantID, auth.clientID, func(ctx context.Context) (string, error) {
+		return requestOIDCToken(ctx, auth.oidcRequestURL, auth.oidcRequestToken)
+	}, &azidentity.ClientAssertionCredentialOptions{ClientOptions: clientOptions})
+	if err != nil {
+		return nil, fmt.Errorf("OIDC: %w", err)
+	}
+	return cred, nil
+}
+
+// requestOIDCToken requests an ID token for Azure to the GitHub Actions token endpoint
+func requestOIDCToken(ctx context.Context, requestURL, requestToken string) (string, error) {
+	tokenURL, err := url.Parse(requestURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid OIDC request URL: %w", err)
+	}
+	query := tokenURL.Query()
+	query.Set("audience", oidcTokenAudience)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("requesting OIDC token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("requesting OIDC token: unexpected status %s", resp.Status)
+	}
+
+	var token struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("decoding OIDC token: %w", err)
+	}
+	if token.Value == "" {
+		return "", errors.New("the OIDC token response has no value")
+	}
+	return token.Value, nil
+}
+
+func stringOrEnv(value types.String, envVar string) string {
+	if !value.IsNull() && !value.IsUnknown() {
+		return value.ValueString()
+	}
+	return os.Getenv(envVar)
+}
+
+func firstEnv(envVars ...string) string {
+	for _, envVar := range envVars {
+		if value := os.Getenv(envVar); value != "" {
+			return value
+		}
+	}
+	return ""
+}
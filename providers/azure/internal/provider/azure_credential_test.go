@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// clearAzureAuthEnv keeps the environment of the machine running the tests out of the authentication config
+func clearAzureAuthEnv(t *testing.T) {
+	for _, envVar := range []string{
+		"ARM_USE_CLI", "ARM_USE_MSI", "ARM_USE_OIDC", "ARM_CLIENT_ID", "ARM_TENANT_ID", "ARM_OIDC_TOKEN_FILE_PATH",
+		"ARM_OIDC_REQUEST_URL", "ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_TOKEN",
+	} {
+		t.Setenv(envVar, "")
+	}
+}
+
+func TestNewAzureAuthConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   dxProviderModel
+		env      map[string]string
+		expected azureAuthConfig
+	}{
+		{
+			name:     "nothing configured",
+			expected: azureAuthConfig{useCLI: true},
+		},
+		{
+			name: "attributes",
+			config: dxProviderModel{
+				UseCLI:   types.BoolValue(false),
+				UseMSI:   types.BoolValue(true),
+				ClientID: types.StringValue("client"),
+			},
+			expected: azureAuthConfig{useMSI: true, clientID: "client", explicit: true},
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				"ARM_USE_OIDC":                   "true",
+				"ARM_CLIENT_ID":                  "client",
+				"ARM_TENANT_ID":                  "tenant",
+				"ACTIONS_ID_TOKEN_REQUEST_URL":   "https://token.actions.example.com",
+				"ACTIONS_ID_TOKEN_REQUEST_TOKEN": "request-token",
+			},
+			expected: azureAuthConfig{
+				useCLI:           true,
+				useOIDC:          true,
+				clientID:         "client",
+				tenantID:         "tenant",
+				oidcRequestURL:   "https://token.actions.example.com",
+				oidcRequestToken: "request-token",
+				explicit:         true,
+			},
+		},
+		{
+			name: "attributes override the environment",
+			config: dxProviderModel{
+				UseOIDC:  types.BoolValue(false),
+				TenantID: types.StringValue("tenant"),
+			},
+			env:      map[string]string{"ARM_USE_OIDC": "true", "ARM_TENANT_ID": "other"},
+			expected: azureAuthConfig{useCLI: true, tenantID: "tenant", explicit: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearAzureAuthEnv(t)
+			for envVar, value := range tt.env {
+				t.Setenv(envVar, value)
+			}
+
+			auth, err := newAzureAuthConfig(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if auth != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, auth)
+			}
+		})
+	}
+}
+
+func TestNewAzureAuthConfig_InvalidEnv(t *testing.T) {
+	clearAzureAuthEnv(t)
+	t.Setenv("ARM_USE_MSI", "maybe")
+
+	if _, err := newAzureAuthConfig(dxProviderModel{}); err == nil || !strings.Contains(err.Error(), "ARM_USE_MSI") {
+		t.Errorf("expected an error about ARM_USE_MSI, got %v", err)
+	}
+}
+
+func TestNewAzureCredential_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		auth     azureAuthConfig
+		expected string
+	}{
+		{
+			name:     "no method enabled",
+			auth:     azureAuthConfig{explicit: true},
+			expected: "no authentication method enabled",
+		},
+		{
+			name:     "OIDC without client ID",
+			auth:     azureAuthConfig{useOIDC: true, tenantID: "tenant", oidcTokenFilePath: "/token", explicit: true},
+			expected: "requires client_id and tenant_id",
+		},
+		{
+			name:     "OIDC without token",
+			auth:     azureAuthConfig{useOIDC: true, clientID: "client", tenantID: "tenant", explicit: true},
+			expected: "requires oidc_token_file_path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := newAzureCredential(tt.auth, cloud.AzurePublic); err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewAzureCredential(t *testing.T) {
+	t.Parallel()
+
+	for name, auth := range map[string]azureAuthConfig{
+		"default":            {useCLI: true},
+		"CLI and MSI":        {useCLI: true, useMSI: true, clientID: "client", explicit: true},
+		"OIDC token file":    {useOIDC: true, clientID: "client", tenantID: "tenant", oidcTokenFilePath: "/token", explicit: true},
+		"OIDC GitHub":        {useOIDC: true, clientID: "client", tenantID: "tenant", oidcRequestURL: "https://token.actions.example.com", oidcRequestToken: "request-token", explicit: true},
+		"OIDC, MSI then CLI": {useCLI: true, useMSI: true, useOIDC: true, clientID: "client", tenantID: "tenant", oidcTokenFilePath: "/token", explicit: true},
+	} {
+		if cred, err := newAzureCredential(auth, cloud.AzurePublic); err != nil || cred == nil {
+			t.Errorf("%s: expected a credential, got %v", name, err)
+		}
+	}
+}
+
+func TestRequestOIDCToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("audience") != oidcTokenAudience || r.URL.Query().Get("api-version") != "2.0" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value": "id-token"}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// GitHub hands out request URLs already having query parameters
+	token, err := requestOIDCToken(ctx, server.URL+"?api-version=2.0", "request-token")
+	if err != nil || token != "id-token" {
+		t.Errorf("expected id-token, got %q (%v)", token, err)
+	}
+
+	if _, err := requestOIDCToken(ctx, server.URL+"?api-version=2.0", "wrong-token"); err == nil {
+		t.Error("expected an error for a rejected request token")
+	}
+}
+
+func TestProviderAuthenticationMisconfigured(t *testing.T) {
+	clearAzureAuthEnv(t)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "dx" {
+  use_oidc = true
+  use_cli  = false
+}
+
+data "dx_resource_types" "all" {}
+`,
+				ExpectError: regexp.MustCompile(`requires client_id and[\s\n]+tenant_id`),
+			},
+		},
+	})
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"net"
+	"strings"
+	"sync"
+)
+
+// subnetCidrAllocator is shared by every dx_available_subnet_cidr resource of the provider process
+var subnetCidrAllocator = newCidrAllocator()
+
+// cidrAllocator serialises CIDR allocations per VNet within the provider process and remembers
+// the blocks handed out but not yet materialised as subnets, so resources created in parallel
+// during the same apply always get disjoint blocks.
+type cidrAllocator struct {
+	mu       sync.Mutex
+	networks map[string]*networkAllocations
+}
+
+// networkAllocations holds the CIDR blocks handed out for a single VNet
+type networkAllocations struct {
+	mu       sync.Mutex
+	reserved map[string]*net.IPNet
+}
+
+func newCidrAllocator() *cidrAllocator {
+	return &cidrAllocator{
+		networks: make(map[string]*networkAllocations),
+	}
+}
+
+// lock acquires the allocation lock of a network and returns its allocations.
+// The returned function releases the lock and must always be called.
+func (a *cidrAllocator) lock(networkID string) (*networkAllocations, func()) {
+	key := strings.ToLower(networkID)
+
+	a.mu.Lock()
+	allocations, ok := a.networks[key]
+	if !ok {
+		allocations = &networkAllocations{reserved: make(map[string]*net.IPNet)}
+		a.networks[key] = allocations
+	}
+	a.mu.Unlock()
+
+	allocations.mu.Lock()
+	return allocations, allocations.mu.Unlock
+}
+
+// release forgets a CIDR block handed out for a network, e.g. when its resource is deleted
+func (a *cidrAllocator) release(networkID, cidrBlock string) {
+	allocations, unlock := a.lock(networkID)
+	defer unlock()
+
+	allocations.release(cidrBlock)
+}
+
+// reservedBlocks returns the CIDR blocks handed out so far. The caller must hold the lock.
+func (n *networkAllocations) reservedBlocks() []*net.IPNet {
+	blocks := make([]*net.IPNet, 0, len(n.reserved))
+	for _, block := range n.reserved {
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// reserve records a CIDR block as handed out. The caller must hold the lock.
+func (n *networkAllocations) reserve(cidrBlock string) error {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return err
+	}
+	n.reserved[block.String()] = block
+	return nil
+}
+
+// release forgets a CIDR block handed out, e.g. when its reservation fails. The caller must hold the lock.
+func (n *networkAllocations) release(cidrBlock string) {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return
+	}
+	delete(n.reserved, block.String())
+}
+
+// cidrPreviews remembers the CIDR blocks previewed per VNet while planning. It lives as long as
+// the provider configuration, i.e. a single plan or apply, so the resources planned together are
+// checked against each other.
+type cidrPreviews struct {
+	mu       sync.Mutex
+	networks map[string][]*net.IPNet
+}
+
+func newCidrPreviews() *cidrPreviews {
+	return &cidrPreviews{
+		networks: make(map[string][]*net.IPNet),
+	}
+}
+
+// claim records the blocks previewed for a resource, unless one of them overlaps a block previewed
+// for another resource, which is returned
+func (p *cidrPreviews) claim(networkID string, blocks []*net.IPNet) *net.IPNet {
+	key := strings.ToLower(networkID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, block := range blocks {
+		for _, previewed := range p.networks[key] {
+			if cidrOverlaps(block, previewed) {
+				return previewed
+			}
+		}
+	}
+	p.networks[key] = append(p.networks[key], blocks...)
+	return nil
+}
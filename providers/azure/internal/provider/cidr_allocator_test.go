@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestCidrAllocator_ParallelAllocationsAreDisjoint(t *testing.T) {
+	t.Parallel()
+
+	allocator := newCidrAllocator()
+	vnetID := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+	addressSpace := "10.0.0.0/16"
+	_, existing, _ := net.ParseCIDR("10.0.0.0/24")
+
+	const count = 20
+	results := make(chan string, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Same steps as Create: lock the VNet, skip reserved blocks, reserve the result
+			allocations, unlock := allocator.lock(vnetID)
+			defer unlock()
+
+			used := append([]*net.IPNet{existing}, allocations.reservedBlocks()...)
+			block := selectAvailableCidrBlock(context.Background(), []*string{&addressSpace}, used, 24, cidrAllocationOptions{})
+			if err := allocations.reserve(block); err != nil {
+				t.Errorf("unexpected error reserving %q: %s", block, err)
+			}
+			results <- block
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := map[string]bool{}
+	for block := range results {
+		if block == "10.0.0.0/24" {
+			t.Errorf("block %s overlaps the existing subnet", block)
+		}
+		if seen[block] {
+			t.Errorf("block %s allocated more than once", block)
+		}
+		seen[block] = true
+	}
+}
+
+func TestCidrAllocator_Release(t *testing.T) {
+	t.Parallel()
+
+	allocator := newCidrAllocator()
+	vnetID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+
+	allocations, unlock := allocator.lock(vnetID)
+	if err := allocations.reserve("10.0.1.0/24"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	unlock()
+
+	// VNet IDs are case insensitive
+	allocator.release("/subscriptions/sub/resourcegroups/rg/providers/microsoft.network/virtualnetworks/vnet", "10.0.1.0/24")
+
+	allocations, unlock = allocator.lock(vnetID)
+	defer unlock()
+	if blocks := allocations.reservedBlocks(); len(blocks) != 0 {
+		t.Errorf("expected no reserved blocks, got %v", blocks)
+	}
+}
@@ -0,0 +1,313 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// Backends available for the subnet_cidr_reservation provider attribute
+const (
+	reservationBackendVirtualNetworkTags = "virtual_network_tags"
+	reservationBackendLocalFile          = "local_file"
+)
+
+// errCidrBlockReserved is returned by Reserve for a block overlapping one another run reserved first
+var errCidrBlockReserved = errors.New("already reserved by another run")
+
+// cidrReservationStore persists the CIDR blocks handed out by dx_available_subnet_cidr,
+// so that reservations are visible to every Terraform run and not only to the state
+// of the workspace that made them.
+type cidrReservationStore interface {
+	// List returns the CIDR blocks reserved in a VNet
+	List(ctx context.Context, vnetID string) ([]string, error)
+	// Reserve records a CIDR block as reserved in a VNet. It fails with errCidrBlockReserved
+	// when the block overlaps a reserved one, so two runs never get overlapping blocks.
+	Reserve(ctx context.Context, vnetID, cidrBlock string) error
+	// Release removes the reservation of a CIDR block, if any
+	Release(ctx context.Context, vnetID, cidrBlock string) error
+}
+
+// reservedCidrBlocks returns the blocks reserved in a VNet, or nil when no store is configured
+func reservedCidrBlocks(ctx context.Context, store cidrReservationStore, vnetID string) ([]*net.IPNet, error) {
+	if store == nil {
+		return nil, nil
+	}
+
+	reservations, err := store.List(ctx, vnetID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*net.IPNet, 0, len(reservations))
+	for _, reservation := range reservations {
+		_, block, err := net.ParseCIDR(reservation)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserved CIDR '%s': %w", reservation, err)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// checkReservable returns errCidrBlockReserved when a block overlaps one of the reservations.
+// Reservations that can't be parsed are skipped, they can't be told apart from free space.
+func checkReservable(reservations []string, cidrBlock string) error {
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR block '%s': %w", cidrBlock, err)
+	}
+
+	for _, reservation := range reservations {
+		_, reserved, err := net.ParseCIDR(reservation)
+		if err == nil && cidrOverlaps(block, reserved) {
+			return errCidrBlockReserved
+		}
+	}
+	return nil
+}
+
+// reserveCidrBlock hands out a block in this provider process and reserves it in the store, if any.
+// The caller must hold the lock of allocations. A block the store fails to reserve is not handed out.
+func reserveCidrBlock(ctx context.Context, store cidrReservationStore, allocations *networkAllocations, vnetID, cidrBlock string) error {
+	if err := allocations.reserve(cidrBlock); err != nil {
+		return err
+	}
+	if store == nil {
+		return nil
+	}
+
+	if err := store.Reserve(ctx, vnetID, cidrBlock); err != nil {
+		allocations.release(cidrBlock)
+		return err
+	}
+	return nil
+}
+
+// releaseCidrBlocks forgets blocks handed out by this provider process and releases their reservations.
+// A reservation left behind only wastes address space, so failures are warnings.
+func releaseCidrBlocks(ctx context.Context, store cidrReservationStore, vnetID string, cidrBlocks []string) diag.Diagnostics {
+	allocations, unlock := subnetCidrAllocator.lock(vnetID)
+	defer unlock()
+
+	return releaseLockedCidrBlocks(ctx, store, allocations, vnetID, cidrBlocks)
+}
+
+// releaseLockedCidrBlocks is releaseCidrBlocks for a caller already holding the lock of allocations
+func releaseLockedCidrBlocks(ctx context.Context, store cidrReservationStore, allocations *networkAllocations, vnetID string, cidrBlocks []string) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	for _, cidrBlock := range cidrBlocks {
+		allocations.release(cidrBlock)
+
+		if store == nil {
+			continue
+		}
+		if err := store.Release(ctx, vnetID, cidrBlock); err != nil {
+			diagnostics.AddWarning(
+				"CIDR Reservation Not Released",
+				fmt.Sprintf("Failed to release CIDR block '%s', remove its reservation manually: %s", cidrBlock, err),
+			)
+		}
+	}
+	return diagnostics
+}
+
+// memoryReservationStore keeps reservations in memory, it is meant for tests
+type memoryReservationStore struct {
+	mu           sync.Mutex
+	reservations map[string]map[string]bool
+}
+
+func newMemoryReservationStore() *memoryReservationStore {
+	return &memoryReservationStore{reservations: make(map[string]map[string]bool)}
+}
+
+func (s *memoryReservationStore) List(_ context.Context, vnetID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedKeys(s.reservations[strings.ToLower(vnetID)]), nil
+}
+
+func (s *memoryReservationStore) Reserve(_ context.Context, vnetID, cidrBlock string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(vnetID)
+	if err := checkReservable(sortedKeys(s.reservations[key]), cidrBlock); err != nil {
+		return err
+	}
+	if s.reservations[key] == nil {
+		s.reservations[key] = make(map[string]bool)
+	}
+	s.reservations[key][cidrBlock] = true
+	return nil
+}
+
+func (s *memoryReservationStore) Release(_ context.Context, vnetID, cidrBlock string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.reservations[strings.ToLower(vnetID)], cidrBlock)
+	return nil
+}
+
+// fileReservationStore keeps reservations in a local JSON file, mapping VNet IDs to CIDR blocks.
+// It is meant for tests and single-machine setups: writers are serialised by a lock file next to
+// it, shared by every provider process of the machine.
+type fileReservationStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// fileLockPollInterval is how often a writer checks whether the lock file was removed
+const fileLockPollInterval = 50 * time.Millisecond
+
+// fileLockTimeout bounds the wait for a lock file held by another run
+const fileLockTimeout = 30 * time.Second
+
+func newFileReservationStore(path string) *fileReservationStore {
+	return &fileReservationStore{path: path}
+}
+
+func (s *fileReservationStore) List(_ context.Context, vnetID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservations, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return reservations[strings.ToLower(vnetID)], nil
+}
+
+func (s *fileReservationStore) Reserve(ctx context.Context, vnetID, cidrBlock string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reservations, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	key := strings.ToLower(vnetID)
+	if err := checkReservable(reservations[key], cidrBlock); err != nil {
+		return err
+	}
+	reservations[key] = append(reservations[key], cidrBlock)
+	sort.Strings(reservations[key])
+	return s.save(reservations)
+}
+
+func (s *fileReservationStore) Release(ctx context.Context, vnetID, cidrBlock string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	reservations, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	key := strings.ToLower(vnetID)
+	kept := reservations[key][:0]
+	for _, reservation := range reservations[key] {
+		if reservation != cidrBlock {
+			kept = append(kept, reservation)
+		}
+	}
+	if len(kept) == 0 {
+		delete(reservations, key)
+	} else {
+		reservations[key] = kept
+	}
+	return s.save(reservations)
+}
+
+// lock creates the lock file of the reservations file, waiting while another run holds it.
+// The returned function removes it and must always be called. A lock file left behind by a
+// crashed run must be removed manually.
+func (s *fileReservationStore) lock(ctx context.Context) (func(), error) {
+	lockPath := s.path + ".lock"
+
+	ctx, cancel := context.WithTimeout(ctx, fileLockTimeout)
+	defer cancel()
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = file.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("locking reservations file '%s': %w", s.path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("reservations file '%s' is locked by another run, remove '%s' if none is running", s.path, lockPath)
+		case <-time.After(fileLockPollInterval):
+		}
+	}
+}
+
+func (s *fileReservationStore) load() (map[string][]string, error) {
+	reservations := make(map[string][]string)
+
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return reservations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading reservations file '%s': %w", s.path, err)
+	}
+
+	if err := json.Unmarshal(content, &reservations); err != nil {
+		return nil, fmt.Errorf("parsing reservations file '%s': %w", s.path, err)
+	}
+	return reservations, nil
+}
+
+func (s *fileReservationStore) save(reservations map[string][]string) error {
+	content, err := json.MarshalIndent(reservations, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see a partial file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("writing reservations file '%s': %w", s.path, err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testVNetID = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+
+func TestCidrReservationStores(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]func(t *testing.T) cidrReservationStore{
+		"memory": func(t *testing.T) cidrReservationStore {
+			return newMemoryReservationStore()
+		},
+		"local file": func(t *testing.T) cidrReservationStore {
+			return newFileReservationStore(filepath.Join(t.TempDir(), "reservations.json"))
+		},
+	}
+
+	for name, newStore := range stores {
+		newStore := newStore
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			store := newStore(t)
+
+			for _, block := range []string{"10.0.2.0/24", "10.0.1.0/24"} {
+				if err := store.Reserve(ctx, testVNetID, block); err != nil {
+					t.Fatalf("unexpected error reserving %s: %s", block, err)
+				}
+			}
+			// A block is reserved once, a second run must pick another one
+			if err := store.Reserve(ctx, testVNetID, "10.0.1.0/24"); !errors.Is(err, errCidrBlockReserved) {
+				t.Errorf("expected reserving a reserved block to fail with %q, got %v", errCidrBlockReserved, err)
+			}
+			// Nor a block overlapping it
+			if err := store.Reserve(ctx, testVNetID, "10.0.1.0/25"); !errors.Is(err, errCidrBlockReserved) {
+				t.Errorf("expected reserving a block inside a reserved one to fail with %q, got %v", errCidrBlockReserved, err)
+			}
+			if err := store.Release(ctx, testVNetID, "10.0.2.0/24"); err != nil {
+				t.Fatalf("unexpected error releasing: %s", err)
+			}
+
+			// VNet IDs are case insensitive
+			reserved, err := store.List(ctx, "/subscriptions/00000000-0000-0000-0000-000000000000/resourcegroups/rg/providers/microsoft.network/virtualnetworks/vnet")
+			if err != nil {
+				t.Fatalf("unexpected error listing: %s", err)
+			}
+			if !reflect.DeepEqual(reserved, []string{"10.0.1.0/24"}) {
+				t.Errorf("expected [10.0.1.0/24], got %v", reserved)
+			}
+
+			blocks, err := reservedCidrBlocks(ctx, store, testVNetID)
+			if err != nil || len(blocks) != 1 || blocks[0].String() != "10.0.1.0/24" {
+				t.Errorf("expected the reserved block to be parsed, got %v (%v)", blocks, err)
+			}
+		})
+	}
+}
+
+func TestCidrReservationStores_FileIsSharedAcrossInstances(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "reservations.json")
+
+	// Two stores on the same file behave like two Terraform runs on the same machine
+	if err := newFileReservationStore(path).Reserve(ctx, testVNetID, "10.0.1.0/24"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	reserved, err := newFileReservationStore(path).List(ctx, testVNetID)
+	if err != nil || !reflect.DeepEqual(reserved, []string{"10.0.1.0/24"}) {
+		t.Errorf("expected [10.0.1.0/24], got %v (%v)", reserved, err)
+	}
+}
+
+func TestCidrReservationStores_FileIsLocked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "reservations.json")
+
+	// Each store stands for a Terraform run, the lock file keeps their updates from overwriting each other
+	const runs = 10
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := newFileReservationStore(path).Reserve(ctx, testVNetID, fmt.Sprintf("10.0.%d.0/24", i)); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	reserved, err := newFileReservationStore(path).List(ctx, testVNetID)
+	if err != nil || len(reserved) != runs {
+		t.Errorf("expected %d reservations, got %v (%v)", runs, reserved, err)
+	}
+
+	// A lock file left behind blocks the writers until they give up
+	if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	err = newFileReservationStore(path).Reserve(timeout, testVNetID, "10.0.100.0/24")
+	if err == nil || !strings.Contains(err.Error(), "is locked by another run") {
+		t.Errorf("expected a lock error, got %v", err)
+	}
+}
+
+func TestVNetTagReservationStore(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-store", "10.0.0.0/16")
+	store := newVNetTagReservationStore(fake.network(t))
+
+	// A run writing the VNet between the read and the write of the store fails the conditional
+	// write, which is retried on top of the new tags
+	fake.writeConcurrently(map[string]string{"owner": "platform"})
+	if err := store.Reserve(ctx, vnetID, "10.0.1.0/24"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tags := fake.tags(vnetID)
+	if _, ok := tags["dx-subnet-cidr-10.0.1.0_24"]; !ok || tags["owner"] != "platform" {
+		t.Errorf("expected both the reservation and the concurrent tag, got %v", tags)
+	}
+
+	// A block reserved by another run in the meantime is not taken twice, nor overlapped
+	fake.writeConcurrently(map[string]string{"dx-subnet-cidr-10.0.2.0_24": "2026-01-01T00:00:00Z"})
+	if err := store.Reserve(ctx, vnetID, "10.0.2.0/24"); !errors.Is(err, errCidrBlockReserved) {
+		t.Errorf("expected %q, got %v", errCidrBlockReserved, err)
+	}
+	if err := store.Reserve(ctx, vnetID, "10.0.2.128/25"); !errors.Is(err, errCidrBlockReserved) {
+		t.Errorf("expected %q, got %v", errCidrBlockReserved, err)
+	}
+
+	if err := store.Release(ctx, vnetID, "10.0.1.0/24"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	reserved, err := store.List(ctx, vnetID)
+	if err != nil || !reflect.DeepEqual(reserved, []string{"10.0.2.0/24"}) {
+		t.Errorf("expected [10.0.2.0/24], got %v (%v)", reserved, err)
+	}
+}
+
+func TestReservationTagName(t *testing.T) {
+	t.Parallel()
+
+	name := tagNameFromCidrBlock("10.0.1.0/24")
+	if name != "dx-subnet-cidr-10.0.1.0_24" {
+		t.Errorf("unexpected tag name %s", name)
+	}
+	if block, ok := cidrBlockFromTagName(name); !ok || block != "10.0.1.0/24" {
+		t.Errorf("expected 10.0.1.0/24, got %s", block)
+	}
+	if _, ok := cidrBlockFromTagName("environment"); ok {
+		t.Error("tags not holding reservations must be ignored")
+	}
+}
+
+func TestProviderSubnetCidrReservation_LocalFileRequiresPath(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "dx" {
+  subnet_cidr_reservation = {
+    backend = "local_file"
+  }
+}
+
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+}
+`, testVNetID),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`The local_file backend requires the path attribute`),
+			},
+		},
+	})
+}
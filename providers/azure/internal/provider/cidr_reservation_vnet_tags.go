@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// reservationTagPrefix prefixes the VNet tags holding CIDR reservations.
+// Azure tag names can't contain "/", so it is replaced with "_" (e.g. dx-subnet-cidr-10.0.1.0_24).
+const reservationTagPrefix = "dx-subnet-cidr-"
+
+// reservationTagAttempts is the number of times a reservation is written when a concurrent
+// run changes the VNet in the meantime
+const reservationTagAttempts = 3
+
+// vnetTagReservationStore keeps reservations as tags of the VNet itself, so they are visible
+// to every Terraform run having access to the VNet. Azure allows 50 tags per resource,
+// including the ones not managed by this provider.
+type vnetTagReservationStore struct {
+	network networkClient
+}
+
+func newVNetTagReservationStore(network networkClient) *vnetTagReservationStore {
+	return &vnetTagReservationStore{network: network}
+}
+
+func (s *vnetTagReservationStore) List(ctx context.Context, vnetID string) ([]string, error) {
+	parsedID, err := parseVNetID(vnetID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tags(ctx, parsedID)
+	if err != nil {
+		return nil, err
+	}
+	return reservationsFromTags(tags), nil
+}
+
+func (s *vnetTagReservationStore) Reserve(ctx context.Context, vnetID, cidrBlock string) error {
+	name := tagNameFromCidrBlock(cidrBlock)
+	return s.update(ctx, vnetID, func(tags map[string]*string) (bool, error) {
+		if err := checkReservable(reservationsFromTags(tags), cidrBlock); err != nil {
+			return false, err
+		}
+		value := time.Now().UTC().Format(time.RFC3339)
+		tags[name] = &value
+		return true, nil
+	})
+}
+
+func (s *vnetTagReservationStore) Release(ctx context.Context, vnetID, cidrBlock string) error {
+	name := tagNameFromCidrBlock(cidrBlock)
+	return s.update(ctx, vnetID, func(tags map[string]*string) (bool, error) {
+		if _, ok := tags[name]; !ok {
+			return false, nil
+		}
+		delete(tags, name)
+		return true, nil
+	})
+}
+
+// update applies a change to the VNet tags, which returns whether there is anything to write.
+// Tags can only be replaced as a whole, so they are written back conditionally on the ETag of the
+// VNet read: when another run changed the VNet in the meantime, the change is applied again to
+// the new tags, so a reservation made concurrently is never overwritten nor overlapped.
+func (s *vnetTagReservationStore) update(ctx context.Context, vnetID string, change func(map[string]*string) (bool, error)) error {
+	parsedID, err := parseVNetID(vnetID)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < reservationTagAttempts; attempt++ {
+		vnet, err := s.network.getVirtualNetwork(ctx, parsedID)
+		if err != nil {
+			return err
+		}
+
+		tags := make(map[string]*string, len(vnet.Tags))
+		for name, value := range vnet.Tags {
+			tags[name] = value
+		}
+		write, err := change(tags)
+		if err != nil || !write {
+			return err
+		}
+		if vnet.Etag == nil {
+			return fmt.Errorf("Virtual Network '%s' has no ETag, its tags can't be updated safely", parsedID.vnetName)
+		}
+
+		err = s.network.updateVirtualNetworkTags(ctx, parsedID, *vnet.Etag, tags)
+		if !isAzurePreconditionFailed(err) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("Virtual Network '%s' was changed concurrently %d times, retry later", parsedID.vnetName, reservationTagAttempts)
+}
+
+func (s *vnetTagReservationStore) tags(ctx context.Context, parsedID *parsedVNetID) (map[string]*string, error) {
+	vnet, err := s.network.getVirtualNetwork(ctx, parsedID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]*string, len(vnet.Tags))
+	for name, value := range vnet.Tags {
+		tags[name] = value
+	}
+	return tags, nil
+}
+
+// reservationsFromTags returns the CIDR blocks reserved by the tags of a VNet
+func reservationsFromTags(tags map[string]*string) []string {
+	var reservations []string
+	for name := range tags {
+		if cidrBlock, ok := cidrBlockFromTagName(name); ok {
+			reservations = append(reservations, cidrBlock)
+		}
+	}
+	return reservations
+}
+
+func tagNameFromCidrBlock(cidrBlock string) string {
+	return reservationTagPrefix + strings.ReplaceAll(cidrBlock, "/", "_")
+}
+
+func cidrBlockFromTagName(name string) (string, bool) {
+	if !strings.HasPrefix(name, reservationTagPrefix) {
+		return "", false
+	}
+
+	cidrBlock := strings.ReplaceAll(strings.TrimPrefix(name, reservationTagPrefix), "_", "/")
+	if _, _, err := net.ParseCIDR(cidrBlock); err != nil {
+		return "", false
+	}
+	return cidrBlock, true
+}
@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"sort"
+
+	"github.com/apparentlymart/go-cidr/cidr"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Strategies available for the strategy attribute of dx_available_subnet_cidr
+const (
+	// cidrStrategyFirstFit takes the lowest free block
+	cidrStrategyFirstFit = "first_fit"
+	// cidrStrategyBestFit takes a block from the smallest free gap that fits it
+	cidrStrategyBestFit = "best_fit"
+	// cidrStrategyLastFit takes the highest free block, keeping the bottom for large subnets
+	cidrStrategyLastFit = "last_fit"
+	// cidrStrategyAligned takes the block at the requested offset, or nothing
+	cidrStrategyAligned = "aligned"
+)
+
+func cidrStrategies() []string {
+	return []string{cidrStrategyFirstFit, cidrStrategyBestFit, cidrStrategyLastFit, cidrStrategyAligned}
+}
+
+// cidrAllocationOptions tune where selectAvailableCidrBlock places a block
+type cidrAllocationOptions struct {
+	// strategy is one of cidrStrategies, empty means first_fit
+	strategy string
+	// offset is the index of the block among the ones of the requested size, used by the aligned strategy
+	offset int
+	// within restricts the candidates to a range of the VNet, nil to use the whole address space
+	within *net.IPNet
+	// exclude lists ranges the block must not overlap, on top of the used blocks
+	exclude []*net.IPNet
+	// ipv6 allocates from the IPv6 address prefixes instead of the IPv4 ones
+	ipv6 bool
+}
+
+// selectAvailableCidrBlock returns a block of the given prefix length, within the VNet address
+// prefixes, that doesn't overlap any of the used blocks. The block is chosen according to the
+// options strategy, and "" is returned when none is free.
+func selectAvailableCidrBlock(ctx context.Context, vnetAddressPrefixes []*string, existingSubnetCIDRs []*net.IPNet, desiredPrefixLen int, options cidrAllocationOptions) string {
+	// within restricts the block of its own IP version only
+	within := options.within
+	if within != nil && (within.IP.To4() == nil) != options.ipv6 {
+		within = nil
+	}
+
+	ranges := candidateRanges(ctx, vnetAddressPrefixes, desiredPrefixLen, within, options.ipv6)
+	existingSubnetCIDRs = append(append([]*net.IPNet{}, existingSubnetCIDRs...), options.exclude...)
+
+	var found *net.IPNet
+	switch options.strategy {
+	case cidrStrategyLastFit:
+		for i := len(ranges) - 1; i >= 0 && found == nil; i-- {
+			found = scanRange(ranges[i], desiredPrefixLen, existingSubnetCIDRs, true)
+		}
+	case cidrStrategyBestFit:
+		found = bestFit(ranges, desiredPrefixLen, existingSubnetCIDRs)
+	case cidrStrategyAligned:
+		if len(ranges) > 0 {
+			found = alignedBlock(ranges[0], desiredPrefixLen, options.offset, existingSubnetCIDRs)
+		}
+	default:
+		for i := 0; i < len(ranges) && found == nil; i++ {
+			found = scanRange(ranges[i], desiredPrefixLen, existingSubnetCIDRs, false)
+		}
+	}
+
+	if found == nil {
+		return ""
+	}
+	tflog.Info(ctx, "Found available CIDR", map[string]interface{}{"cidr": found.String(), "strategy": options.strategy})
+	return found.String()
+}
+
+// candidateRanges returns the ranges where blocks can be allocated: the VNet address prefixes of
+// the IP version large enough for the desired prefix length, or the within range if it is inside one of them
+func candidateRanges(ctx context.Context, vnetAddressPrefixes []*string, desiredPrefixLen int, within *net.IPNet, ipv6 bool) []*net.IPNet {
+	var ranges []*net.IPNet
+
+	for _, vnetPrefixPtr := range vnetAddressPrefixes {
+		vnetPrefix := *vnetPrefixPtr
+		_, vnetNet, err := net.ParseCIDR(vnetPrefix)
+		if err != nil {
+			tflog.Warn(ctx, "Could not parse VNet address prefix", map[string]interface{}{"cidr": vnetPrefix, "error": err})
+			continue
+		}
+
+		if (vnetNet.IP.To4() == nil) != ipv6 {
+			continue
+		}
+
+		vnetPrefixLen, _ := vnetNet.Mask.Size()
+		if desiredPrefixLen <= vnetPrefixLen {
+			tflog.Warn(ctx, "Desired prefix length too small", map[string]interface{}{
+				"desired_length": desiredPrefixLen,
+				"vnet_length":    vnetPrefixLen,
+				"vnet_prefix":    vnetPrefix,
+			})
+			continue
+		}
+
+		if within == nil {
+			ranges = append(ranges, vnetNet)
+			continue
+		}
+
+		withinPrefixLen, _ := within.Mask.Size()
+		if withinPrefixLen >= vnetPrefixLen && withinPrefixLen <= desiredPrefixLen && vnetNet.Contains(within.IP) {
+			return []*net.IPNet{within}
+		}
+	}
+
+	return ranges
+}
+
+// scanRange returns the first free block of a range, or the last one when reverse is set.
+// Only the first or last aligned block of each free gap is a candidate, so the cost depends on
+// the used blocks and not on the size of the range, e.g. a /64 in a /32 IPv6 space.
+func scanRange(rangeNet *net.IPNet, desiredPrefixLen int, used []*net.IPNet, reverse bool) *net.IPNet {
+	gaps := freeGaps(rangeNet, used)
+	size := blockSize(rangeNet, desiredPrefixLen)
+
+	for n := range gaps {
+		gap := gaps[n]
+		if reverse {
+			gap = gaps[len(gaps)-1-n]
+		}
+		if block := gap.edgeBlock(size, reverse); block != nil {
+			return toIPNet(block, desiredPrefixLen, rangeNet)
+		}
+	}
+
+	return nil
+}
+
+// bestFit returns the first block of the smallest free gap that fits it, so that large gaps stay
+// available for large subnets. Among gaps of the same size the lowest one wins.
+func bestFit(ranges []*net.IPNet, desiredPrefixLen int, used []*net.IPNet) *net.IPNet {
+	var best *net.IPNet
+	var bestGapSize *big.Int
+
+	for _, rangeNet := range ranges {
+		size := blockSize(rangeNet, desiredPrefixLen)
+		for _, gap := range freeGaps(rangeNet, used) {
+			block := gap.edgeBlock(size, false)
+			if block == nil {
+				continue
+			}
+
+			gapSize := new(big.Int).Sub(gap.end, gap.start)
+			if bestGapSize == nil || gapSize.Cmp(bestGapSize) < 0 {
+				best = toIPNet(block, desiredPrefixLen, rangeNet)
+				bestGapSize = gapSize
+			}
+		}
+	}
+
+	return best
+}
+
+// alignedBlock returns the block at the given offset of a range, if it is free
+func alignedBlock(rangeNet *net.IPNet, desiredPrefixLen, offset int, used []*net.IPNet) *net.IPNet {
+	rangePrefixLen, _ := rangeNet.Mask.Size()
+	if !offsetInRange(int64(offset), desiredPrefixLen-rangePrefixLen) {
+		return nil
+	}
+
+	candidateNet, err := cidr.Subnet(rangeNet, desiredPrefixLen-rangePrefixLen, offset)
+	if err != nil || overlapsAny(candidateNet, used) {
+		return nil
+	}
+	return candidateNet
+}
+
+// offsetInRange reports whether offset is the index of one of the 2^bits blocks of a range,
+// without overflowing when bits is as large as IPv6 allows
+func offsetInRange(offset int64, bits int) bool {
+	return offset >= 0 && (bits >= 63 || offset < int64(1)<<uint(bits))
+}
+
+// addressGap is a range of free addresses, from start included to end excluded
+type addressGap struct {
+	start, end *big.Int
+}
+
+// edgeBlock returns the start of the first aligned block of the given size in the gap, or of the
+// last one when last is set, nil when no aligned block fits
+func (g addressGap) edgeBlock(size *big.Int, last bool) *big.Int {
+	var block *big.Int
+	if last {
+		// Round end - size down to a multiple of size
+		block = new(big.Int).Sub(g.end, size)
+		if block.Sign() < 0 {
+			return nil
+		}
+		block.Sub(block, new(big.Int).Mod(block, size))
+	} else {
+		// Round start up to a multiple of size
+		block = new(big.Int).Add(g.start, size)
+		block.Sub(block, big.NewInt(1))
+		block.Sub(block, new(big.Int).Mod(block, size))
+	}
+
+	blockEnd := new(big.Int).Add(block, size)
+	if block.Cmp(g.start) < 0 || blockEnd.Cmp(g.end) > 0 {
+		return nil
+	}
+	return block
+}
+
+// freeGaps returns the ranges of rangeNet not covered by the used blocks, in ascending order
+func freeGaps(rangeNet *net.IPNet, used []*net.IPNet) []addressGap {
+	rangeStart, rangeEnd := addressBounds(rangeNet)
+
+	var covered []addressGap
+	for _, block := range used {
+		if (block.IP.To4() == nil) != (rangeNet.IP.To4() == nil) || !cidrOverlaps(block, rangeNet) {
+			continue
+		}
+		start, end := addressBounds(block)
+		covered = append(covered, addressGap{start: maxBig(start, rangeStart), end: minBig(end, rangeEnd)})
+	}
+	sort.Slice(covered, func(i, j int) bool { return covered[i].start.Cmp(covered[j].start) < 0 })
+
+	var gaps []addressGap
+	next := rangeStart
+	for _, block := range covered {
+		if block.start.Cmp(next) > 0 {
+			gaps = append(gaps, addressGap{start: next, end: block.start})
+		}
+		next = maxBig(next, block.end)
+	}
+	if next.Cmp(rangeEnd) < 0 {
+		gaps = append(gaps, addressGap{start: next, end: rangeEnd})
+	}
+	return gaps
+}
+
+// addressBounds returns the first address of a block and the one right after its last
+func addressBounds(block *net.IPNet) (*big.Int, *big.Int) {
+	ip := block.IP.To4()
+	if ip == nil {
+		ip = block.IP.To16()
+	}
+	prefixLen, _ := block.Mask.Size()
+	start := new(big.Int).SetBytes(ip.Mask(block.Mask))
+	return start, new(big.Int).Add(start, blockSize(block, prefixLen))
+}
+
+// blockSize returns the number of addresses of a block of prefix length prefixLen in the IP version of rangeNet
+func blockSize(rangeNet *net.IPNet, prefixLen int) *big.Int {
+	_, bits := rangeNet.Mask.Size()
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-prefixLen))
+}
+
+// toIPNet returns the block of prefix length prefixLen starting at address, in the IP version of rangeNet
+func toIPNet(address *big.Int, prefixLen int, rangeNet *net.IPNet) *net.IPNet {
+	_, bits := rangeNet.Mask.Size()
+	ip := make(net.IP, bits/8)
+	address.FillBytes(ip)
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(prefixLen, bits)}
+}
+
+func minBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) > 0 {
+		return a
+	}
+	return b
+}
+
+func overlapsAny(candidate *net.IPNet, used []*net.IPNet) bool {
+	for _, existingNet := range used {
+		if cidrOverlaps(candidate, existingNet) {
+			return true
+		}
+	}
+	return false
+}
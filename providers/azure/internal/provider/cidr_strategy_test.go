@@ -0,0 +1,236 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestSelectAvailableCidrBlock_Strategies(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := "10.0.0.0/24"
+	// Free: 10.0.0.16/28 (a /28 gap) and 10.0.0.64/26, 10.0.0.128/25 (a /26 and a /25 gap)
+	used := []*net.IPNet{
+		mustParseCIDR(t, "10.0.0.0/28"),
+		mustParseCIDR(t, "10.0.0.32/27"),
+	}
+
+	tests := []struct {
+		name         string
+		prefixLength int
+		options      cidrAllocationOptions
+		expected     string
+	}{
+		{"default is first fit", 28, cidrAllocationOptions{}, "10.0.0.16/28"},
+		{"first fit", 27, cidrAllocationOptions{strategy: cidrStrategyFirstFit}, "10.0.0.64/27"},
+		{"last fit", 28, cidrAllocationOptions{strategy: cidrStrategyLastFit}, "10.0.0.240/28"},
+		{"best fit fills the smallest gap", 28, cidrAllocationOptions{strategy: cidrStrategyBestFit}, "10.0.0.16/28"},
+		{"best fit skips gaps too small", 27, cidrAllocationOptions{strategy: cidrStrategyBestFit}, "10.0.0.64/27"},
+		{"aligned", 26, cidrAllocationOptions{strategy: cidrStrategyAligned, offset: 3}, "10.0.0.192/26"},
+		{"aligned block in use", 26, cidrAllocationOptions{strategy: cidrStrategyAligned, offset: 0}, ""},
+		{"aligned offset out of range", 26, cidrAllocationOptions{strategy: cidrStrategyAligned, offset: 4}, ""},
+		{"within", 28, cidrAllocationOptions{within: mustParseCIDR(t, "10.0.0.128/25")}, "10.0.0.128/28"},
+		{"aligned within", 28, cidrAllocationOptions{strategy: cidrStrategyAligned, offset: 1, within: mustParseCIDR(t, "10.0.0.128/25")}, "10.0.0.144/28"},
+		{"within outside the address space", 28, cidrAllocationOptions{within: mustParseCIDR(t, "10.1.0.0/24")}, ""},
+		{"exclude", 28, cidrAllocationOptions{exclude: []*net.IPNet{mustParseCIDR(t, "10.0.0.16/28"), mustParseCIDR(t, "10.0.0.64/26")}}, "10.0.0.128/28"},
+		{"exclude the whole range", 28, cidrAllocationOptions{within: mustParseCIDR(t, "10.0.0.128/25"), exclude: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectAvailableCidrBlock(context.Background(), []*string{&addressSpace}, used, tt.prefixLength, tt.options)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelectAvailableCidrBlock_IPv6(t *testing.T) {
+	t.Parallel()
+
+	ipv4AddressSpace, ipv6AddressSpace := "10.0.0.0/16", "fd00:db8:deca::/48"
+	addressSpace := []*string{&ipv4AddressSpace, &ipv6AddressSpace}
+	used := []*net.IPNet{
+		mustParseCIDR(t, "10.0.0.0/24"),
+		mustParseCIDR(t, "fd00:db8:deca::/64"),
+	}
+
+	tests := []struct {
+		name         string
+		prefixLength int
+		options      cidrAllocationOptions
+		expected     string
+	}{
+		{"IPv4 ignores the IPv6 space", 24, cidrAllocationOptions{}, "10.0.1.0/24"},
+		{"IPv6", 64, cidrAllocationOptions{ipv6: true}, "fd00:db8:deca:1::/64"},
+		{"IPv6 last fit", 64, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyLastFit}, "fd00:db8:deca:ffff::/64"},
+		{"IPv6 ignores an IPv4 within", 64, cidrAllocationOptions{ipv6: true, within: mustParseCIDR(t, "10.0.16.0/20")}, "fd00:db8:deca:1::/64"},
+		{"IPv6 within", 64, cidrAllocationOptions{ipv6: true, within: mustParseCIDR(t, "fd00:db8:deca:100::/56")}, "fd00:db8:deca:100::/64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectAvailableCidrBlock(context.Background(), addressSpace, used, tt.prefixLength, tt.options)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	ipv4Only := []*string{&ipv4AddressSpace}
+	if got := selectAvailableCidrBlock(context.Background(), ipv4Only, used, 64, cidrAllocationOptions{ipv6: true}); got != "" {
+		t.Errorf("expected no IPv6 block in an IPv4 only VNet, got %q", got)
+	}
+}
+
+func TestSelectAvailableCidrBlock_Gaps(t *testing.T) {
+	t.Parallel()
+
+	// Blocks are only taken at the aligned edges of the free gaps, however large the range:
+	// enumerating the 2^48 /64 of a /16, or the 2^80 /128 of a /48, would never end
+	ipv4AddressSpace, ipv6AddressSpace, hostAddressSpace := "10.0.0.0/24", "fd00::/16", "fd01::/48"
+	used := []*net.IPNet{
+		// Free IPv4: 10.0.0.8 - 10.0.0.39 and 10.0.0.48 - 10.0.0.255, neither starting on a /27 boundary
+		mustParseCIDR(t, "10.0.0.0/29"),
+		mustParseCIDR(t, "10.0.0.40/29"),
+		// Free IPv6: fd00:0:0:1::/64, then everything from fd00:0:0:4::
+		mustParseCIDR(t, "fd00::/64"),
+		mustParseCIDR(t, "fd00:0:0:2::/63"),
+		mustParseCIDR(t, "fd01::/127"),
+	}
+
+	tests := []struct {
+		name         string
+		addressSpace string
+		prefixLength int
+		options      cidrAllocationOptions
+		expected     string
+	}{
+		{"first fit skips a gap not holding an aligned block", ipv4AddressSpace, 27, cidrAllocationOptions{}, "10.0.0.64/27"},
+		{"last fit", ipv4AddressSpace, 28, cidrAllocationOptions{strategy: cidrStrategyLastFit}, "10.0.0.240/28"},
+		{"best fit", ipv4AddressSpace, 28, cidrAllocationOptions{strategy: cidrStrategyBestFit}, "10.0.0.16/28"},
+		{"large IPv6 first fit", ipv6AddressSpace, 64, cidrAllocationOptions{ipv6: true}, "fd00:0:0:1::/64"},
+		{"large IPv6 last fit", ipv6AddressSpace, 64, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyLastFit}, "fd00:ffff:ffff:ffff::/64"},
+		{"large IPv6 best fit", ipv6AddressSpace, 64, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyBestFit}, "fd00:0:0:1::/64"},
+		{"large IPv6 aligned", ipv6AddressSpace, 64, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyAligned, offset: 1 << 40}, "fd00:100::/64"},
+		{"more than 63 bits", hostAddressSpace, 128, cidrAllocationOptions{ipv6: true}, "fd01::2/128"},
+		{"more than 63 bits last fit", hostAddressSpace, 128, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyLastFit}, "fd01::ffff:ffff:ffff:ffff:ffff/128"},
+		{"more than 63 bits aligned", hostAddressSpace, 128, cidrAllocationOptions{ipv6: true, strategy: cidrStrategyAligned, offset: 5}, "fd01::5/128"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectAvailableCidrBlock(context.Background(), []*string{&tt.addressSpace}, used, tt.prefixLength, tt.options)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOffsetInRange(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		offset   int64
+		bits     int
+		expected bool
+	}{
+		{0, 0, true},
+		{1, 0, false},
+		{3, 2, true},
+		{4, 2, false},
+		{-1, 2, false},
+		{1 << 62, 63, true},
+		{1 << 62, 80, true},
+	} {
+		if got := offsetInRange(tt.offset, tt.bits); got != tt.expected {
+			t.Errorf("offsetInRange(%d, %d): expected %t, got %t", tt.offset, tt.bits, tt.expected, got)
+		}
+	}
+}
+
+func TestAvailableSubnetCidrResource_InvalidAllocationOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		attributes    string
+		expectedError string
+	}{
+		{"offset without aligned strategy", `offset = 1`, `offset is only used by the aligned strategy`},
+		{"invalid within_cidr", `within_cidr = "10.0.0.0"`, `within_cidr must be in CIDR notation`},
+		{"within_cidr with host bits", `within_cidr = "10.0.0.1/24"`, `did you mean 10.0.0.0/24\?`},
+		{"prefix larger than within_cidr", `within_cidr = "10.0.0.0/25"`, `A /24 block doesn't fit within_cidr`},
+		{"offset out of range", "strategy = \"aligned\"\n  offset = 4\n  within_cidr = \"10.0.0.0/23\"", `holds 2 blocks of /24`},
+		{"invalid exclude_cidrs", `exclude_cidrs = ["10.0.0.0/24", "192.168.0.0"]`, `exclude_cidrs must be in CIDR notation`},
+		{"exclude_cidrs with host bits", `exclude_cidrs = ["192.168.0.1/16"]`, `did you mean 192.168.0.0/16\?`},
+		{"IPv6 within_cidr without ipv6_prefix_length", `within_cidr = "fd00:db8:deca::/56"`, `restricts the block sized by[\s\n]+ipv6_prefix_length`},
+		{"IPv6 prefix length other than 64", `ipv6_prefix_length = 56`, `ipv6_prefix_length value must be one of`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+  %s
+}
+`, testVNetID, tt.attributes),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(tt.expectedError),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestCidrWithinAddressSpace(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := []*net.IPNet{mustParseCIDR(t, "10.0.0.0/16"), mustParseCIDR(t, "172.16.0.0/24")}
+
+	for within, expected := range map[string]bool{
+		"10.0.16.0/20":  true,
+		"10.0.0.0/16":   true,
+		"172.16.0.0/25": true,
+		"10.0.0.0/15":   false,
+		"10.1.0.0/20":   false,
+		"172.16.1.0/24": false,
+	} {
+		if got := cidrWithinAddressSpace(mustParseCIDR(t, within), addressSpace); got != expected {
+			t.Errorf("%s: expected %t, got %t", within, expected, got)
+		}
+	}
+}
+
+func TestAvailableSubnetCidrResource_MissingPrefixLength(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+}
+`, testVNetID),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`At least one of prefix_length and ipv6_prefix_length must be set`),
+			},
+		},
+	})
+}
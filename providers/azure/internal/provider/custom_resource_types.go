@@ -0,0 +1,315 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Naming constraints applied to custom resource types that don't set their own
+const (
+	defaultCustomMinLength = 1
+	defaultCustomMaxLength = 80
+)
+
+// customResourceTypesCategory is the category reported for custom resource types
+const customResourceTypesCategory = "Custom"
+
+var abbreviationPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// customResourceTypeModel is a user-defined resource type, keyed by its resource_type name
+type customResourceTypeModel struct {
+	Abbreviation        types.String `tfsdk:"abbreviation"`
+	MinLength           types.Int64  `tfsdk:"min_length"`
+	MaxLength           types.Int64  `tfsdk:"max_length"`
+	RemoveHyphens       types.Bool   `tfsdk:"remove_hyphens"`
+	MustStartWithLetter types.Bool   `tfsdk:"must_start_with_letter"`
+}
+
+// customResourceTypesAttribute is the provider schema of the custom_resource_types attribute
+func customResourceTypesAttribute() schema.MapNestedAttribute {
+	return schema.MapNestedAttribute{
+		Optional:    true,
+		Description: "Additional resource types, keyed by resource type, listed by the dx_resource_types data source next to the built-in ones. It has no effect on resource_name, resource_names and parse_resource_name, which don't see the provider configuration: pass the same map as their custom_resource_types argument. Entries must not collide with built-in resource types, built-in abbreviations or each other.",
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"abbreviation": schema.StringAttribute{
+					Required:    true,
+					Description: "Abbreviation used in the generated names (lowercase letters and numbers, optionally separated by hyphens)",
+					Validators: []validator.String{
+						stringvalidator.RegexMatches(abbreviationPattern, "must contain lowercase letters and numbers, optionally separated by hyphens"),
+					},
+				},
+				"min_length": schema.Int64Attribute{
+					Optional:    true,
+					Description: fmt.Sprintf("Minimum length of the generated names (defaults to %d)", defaultCustomMinLength),
+					Validators: []validator.Int64{
+						int64validator.AtLeast(1),
+					},
+				},
+				"max_length": schema.Int64Attribute{
+					Optional:    true,
+					Description: fmt.Sprintf("Maximum length of the generated names (defaults to %d)", defaultCustomMaxLength),
+					Validators: []validator.Int64{
+						int64validator.AtLeast(1),
+					},
+				},
+				"remove_hyphens": schema.BoolAttribute{
+					Optional:    true,
+					Description: "Remove hyphens from the generated names, for resources accepting only letters and numbers (defaults to false)",
+				},
+				"must_start_with_letter": schema.BoolAttribute{
+					Optional:    true,
+					Description: "Require the generated names to start with a letter (defaults to false)",
+				},
+			},
+		},
+	}
+}
+
+// customResourceTypeValues holds the plain values of a custom resource type definition
+type customResourceTypeValues struct {
+	abbreviation        string
+	minLength           int64
+	maxLength           int64
+	removeHyphens       bool
+	mustStartWithLetter bool
+}
+
+// toResourceAbbreviation applies the default naming constraints to unset values
+func (v customResourceTypeValues) toResourceAbbreviation() resourceAbbreviation {
+	rule := namingRule{
+		minLength:           defaultCustomMinLength,
+		maxLength:           defaultCustomMaxLength,
+		charset:             charsetAlphanumericHyphens,
+		removeHyphens:       v.removeHyphens,
+		mustStartWithLetter: v.mustStartWithLetter,
+	}
+	if v.minLength > 0 {
+		rule.minLength = int(v.minLength)
+	}
+	if v.maxLength > 0 {
+		rule.maxLength = int(v.maxLength)
+	}
+	if v.removeHyphens {
+		rule.charset = charsetAlphanumeric
+	}
+
+	return resourceAbbreviation{abbreviation: v.abbreviation, rule: rule, category: customResourceTypesCategory}
+}
+
+// extendResourceAbbreviations returns the built-in resource types extended with the custom ones.
+// Custom resource types must not redefine a built-in resource type, nor reuse an abbreviation.
+func extendResourceAbbreviations(custom map[string]customResourceTypeValues) (map[string]resourceAbbreviation, error) {
+	abbreviations := getResourceAbbreviations()
+
+	owners := make(map[string]string, len(abbreviations)+len(custom))
+	for resourceType, resourceAbbr := range abbreviations {
+		owners[resourceAbbr.abbreviation] = resourceType
+	}
+
+	// Sort the resource types so errors are reported deterministically
+	resourceTypes := make([]string, 0, len(custom))
+	for resourceType := range custom {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	for _, resourceType := range resourceTypes {
+		values := custom[resourceType]
+
+		if _, exists := abbreviations[resourceType]; exists {
+			return nil, fmt.Errorf("custom resource type '%s' collides with a built-in resource type", resourceType)
+		}
+
+		if !abbreviationPattern.MatchString(values.abbreviation) {
+			return nil, fmt.Errorf("abbreviation '%s' of custom resource type '%s' must contain lowercase letters and numbers, optionally separated by hyphens", values.abbreviation, resourceType)
+		}
+
+		if owner, exists := owners[values.abbreviation]; exists {
+			return nil, fmt.Errorf("abbreviation '%s' of custom resource type '%s' is already used by resource type '%s'", values.abbreviation, resourceType, owner)
+		}
+
+		resourceAbbr := values.toResourceAbbreviation()
+		if resourceAbbr.rule.minLength > resourceAbbr.rule.maxLength {
+			return nil, fmt.Errorf("min_length of custom resource type '%s' must not be greater than max_length", resourceType)
+		}
+
+		owners[values.abbreviation] = resourceType
+		abbreviations[resourceType] = resourceAbbr
+	}
+
+	if err := validateResourceAbbreviations(abbreviations); err != nil {
+		return nil, err
+	}
+
+	return abbreviations, nil
+}
+
+// customResourceTypesFromConfig reads the custom_resource_types attribute of the provider configuration.
+// The returned map is nil when the attribute is not set or not yet known.
+func customResourceTypesFromConfig(ctx context.Context, value types.Map) (map[string]customResourceTypeValues, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if value.IsNull() || value.IsUnknown() {
+		return nil, diags
+	}
+
+	var models map[string]customResourceTypeModel
+	diags.Append(value.ElementsAs(ctx, &models, false)...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	custom := make(map[string]customResourceTypeValues, len(models))
+	for resourceType, model := range models {
+		if model.Abbreviation.IsUnknown() || model.MinLength.IsUnknown() || model.MaxLength.IsUnknown() ||
+			model.RemoveHyphens.IsUnknown() || model.MustStartWithLetter.IsUnknown() {
+			return nil, diags
+		}
+		custom[resourceType] = customResourceTypeValues{
+			abbreviation:        model.Abbreviation.ValueString(),
+			minLength:           model.MinLength.ValueInt64(),
+			maxLength:           model.MaxLength.ValueInt64(),
+			removeHyphens:       model.RemoveHyphens.ValueBool(),
+			mustStartWithLetter: model.MustStartWithLetter.ValueBool(),
+		}
+	}
+
+	return custom, diags
+}
+
+// validateCustomResourceTypesConfig reports collisions of the custom resource types set in the provider block
+func validateCustomResourceTypesConfig(ctx context.Context, value types.Map) diag.Diagnostics {
+	custom, diags := customResourceTypesFromConfig(ctx, value)
+	if diags.HasError() || custom == nil {
+		return diags
+	}
+
+	if _, err := extendResourceAbbreviations(custom); err != nil {
+		diags.AddAttributeError(path.Root("custom_resource_types"), "Invalid custom resource type", err.Error())
+	}
+
+	return diags
+}
+
+// customResourceTypesParameter is the optional trailing argument of the naming functions.
+// Functions run on an unconfigured provider, so the custom_resource_types of the provider
+// block are not visible to them and must be passed explicitly.
+func customResourceTypesParameter() function.DynamicParameter {
+	return function.DynamicParameter{
+		Name:        "custom_resource_types",
+		Description: "Optional map of additional resource types, with the same format of the custom_resource_types provider attribute (abbreviation, min_length, max_length, remove_hyphens and must_start_with_letter).",
+	}
+}
+
+// customResourceTypesFromArguments reads the optional custom_resource_types argument of the naming
+// functions: a map of resource type to an object with the same attributes of the provider block.
+func customResourceTypesFromArguments(arguments []types.Dynamic) (map[string]resourceAbbreviation, *function.FuncError) {
+	if len(arguments) == 0 {
+		return getResourceAbbreviations(), nil
+	}
+	if len(arguments) > 1 {
+		return nil, function.NewFuncError("The custom_resource_types argument can be passed only once")
+	}
+
+	definitions, err := dynamicMapElements(arguments[0])
+	if err != nil {
+		return nil, function.NewFuncError("The custom_resource_types argument must be a map of resource type to its definition")
+	}
+
+	custom := make(map[string]customResourceTypeValues, len(definitions))
+	for resourceType, definition := range definitions {
+		attributes, ok := definition.(types.Object)
+		if !ok || attributes.IsNull() || attributes.IsUnknown() {
+			return nil, function.NewFuncError(fmt.Sprintf("The definition of custom resource type '%s' must be an object", resourceType))
+		}
+
+		values, funcErr := customResourceTypeFromAttributes(resourceType, attributes.Attributes())
+		if funcErr != nil {
+			return nil, funcErr
+		}
+		custom[resourceType] = values
+	}
+
+	abbreviations, extendErr := extendResourceAbbreviations(custom)
+	if extendErr != nil {
+		return nil, function.NewFuncError(fmt.Sprintf("InvalidCustomResourceType: %s", extendErr))
+	}
+
+	return abbreviations, nil
+}
+
+// customResourceTypeFromAttributes converts the attributes of a custom resource type object
+func customResourceTypeFromAttributes(resourceType string, attributes map[string]attr.Value) (customResourceTypeValues, *function.FuncError) {
+	var values customResourceTypeValues
+	invalid := func(key, expected string) *function.FuncError {
+		return function.NewFuncError(fmt.Sprintf("The '%s' attribute of custom resource type '%s' must be %s", key, resourceType, expected))
+	}
+
+	for key, value := range attributes {
+		if value.IsNull() {
+			continue
+		}
+
+		raw, ok := primitiveToString(value)
+		if !ok {
+			return values, invalid(key, "a primitive value")
+		}
+
+		var err error
+		switch key {
+		case "abbreviation":
+			values.abbreviation = raw
+		case "min_length":
+			values.minLength, err = strconv.ParseInt(raw, 10, 64)
+		case "max_length":
+			values.maxLength, err = strconv.ParseInt(raw, 10, 64)
+		case "remove_hyphens":
+			values.removeHyphens, err = strconv.ParseBool(raw)
+		case "must_start_with_letter":
+			values.mustStartWithLetter, err = strconv.ParseBool(raw)
+		default:
+			return values, function.NewFuncError(fmt.Sprintf("Invalid key in custom resource type '%s'. The key '%s' is not allowed", resourceType, key))
+		}
+		if err != nil {
+			return values, invalid(key, "a valid value")
+		}
+	}
+
+	if values.abbreviation == "" {
+		return values, function.NewFuncError(fmt.Sprintf("Missing key in custom resource type '%s'. The required key 'abbreviation' is missing", resourceType))
+	}
+	if values.minLength < 0 || values.maxLength < 0 {
+		return values, function.NewFuncError(fmt.Sprintf("The length constraints of custom resource type '%s' must be positive", resourceType))
+	}
+
+	return values, nil
+}
+
+// dynamicMapElements returns the elements of a dynamic value holding a map or an object
+func dynamicMapElements(value types.Dynamic) (map[string]attr.Value, error) {
+	if value.IsNull() || value.IsUnderlyingValueNull() {
+		return map[string]attr.Value{}, nil
+	}
+
+	switch v := value.UnderlyingValue().(type) {
+	case types.Map:
+		return v.Elements(), nil
+	case types.Object:
+		return v.Attributes(), nil
+	default:
+		return nil, fmt.Errorf("expected a map, got %s", v.Type(context.Background()))
+	}
+}
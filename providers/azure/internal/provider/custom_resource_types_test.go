@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+// providerCustomResourceTypesConfig declares a resource so that Terraform validates the provider block.
+const providerCustomResourceTypesConfig = `
+provider "dx" {
+  custom_resource_types = %s
+}
+
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+  prefix_length      = 24
+}
+`
+
+func TestProviderCustomResourceTypes(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(providerCustomResourceTypesConfig, `{
+    openai = { abbreviation = "oai", max_length = 64 }
+  }`),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestProviderCustomResourceTypes_Collisions(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		customResourceTypes string
+		expectedError       string
+	}{
+		"built-in resource type": {
+			customResourceTypes: `{ key_vault = { abbreviation = "vault" } }`,
+			expectedError:       `custom resource type 'key_vault' collides with a[\s\n]+built-in resource type`,
+		},
+		"built-in abbreviation": {
+			customResourceTypes: `{ my_vault = { abbreviation = "kv" } }`,
+			expectedError:       `abbreviation 'kv' of custom resource type 'my_vault' is[\s\n]+already used by[\s\n]+resource type 'key_vault'`,
+		},
+		"custom abbreviation": {
+			customResourceTypes: `{
+    openai      = { abbreviation = "oai" }
+    openai_copy = { abbreviation = "oai" }
+  }`,
+			expectedError: `abbreviation 'oai' of custom resource type 'openai_copy' is[\s\n]+already used by[\s\n]+resource type 'openai'`,
+		},
+	}
+
+	for name, tc := range cases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config:      fmt.Sprintf(providerCustomResourceTypesConfig, tc.customResourceTypes),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(tc.expectedError),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestResourceNameFunction_CustomResourceTypes(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+locals {
+  custom_resource_types = {
+    openai        = { abbreviation = "oai" }
+    ai_foundry_st = { abbreviation = "stai", max_length = 24, remove_hyphens = true }
+  }
+}
+
+output "openai" {
+  value = provider::dx::resource_name({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    name            = "chat",
+    resource_type   = "openai",
+    instance_number = 1,
+  }, local.custom_resource_types)
+}
+
+output "names" {
+  value = provider::dx::resource_names({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    name            = "chat",
+    instance_number = 1,
+  }, ["ai_foundry_st", "key_vault"], local.custom_resource_types)
+}
+
+output "parsed" {
+  value = provider::dx::parse_resource_name("dx-d-itn-chat-oai-01", local.custom_resource_types).resource_type
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("openai", knownvalue.StringExact("dx-d-itn-chat-oai-01")),
+					statecheck.ExpectKnownOutputValue("names", knownvalue.MapExact(map[string]knownvalue.Check{
+						"ai_foundry_st": knownvalue.StringExact("dxditnchatstai01"),
+						"key_vault":     knownvalue.StringExact("dx-d-itn-chat-kv-01"),
+					})),
+					statecheck.ExpectKnownOutputValue("parsed", knownvalue.StringExact("openai")),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_CustomResourceTypeCollision(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_name({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    name            = "chat",
+    resource_type   = "my_app",
+    instance_number = 1,
+  }, { my_app = { abbreviation = "app" } })
+}
+`,
+				ExpectError: regexp.MustCompile(`InvalidCustomResourceType:[\s\n]+abbreviation[\s\n]+'app'`),
+			},
+		},
+	})
+}
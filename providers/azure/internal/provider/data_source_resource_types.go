@@ -0,0 +1,142 @@
+// Implementation of the data source listing the resource types supported by the naming functions
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = &resourceTypesDataSource{}
+
+func NewResourceTypesDataSource() datasource.DataSource {
+	return &resourceTypesDataSource{}
+}
+
+// Data source definition
+type resourceTypesDataSource struct {
+	abbreviations map[string]resourceAbbreviation
+}
+
+// Data source model
+type resourceTypesDataSourceModel struct {
+	ResourceTypes []resourceTypeModel `tfsdk:"resource_types"`
+}
+
+type resourceTypeModel struct {
+	ResourceType        types.String `tfsdk:"resource_type"`
+	Abbreviation        types.String `tfsdk:"abbreviation"`
+	Category            types.String `tfsdk:"category"`
+	MinLength           types.Int64  `tfsdk:"min_length"`
+	MaxLength           types.Int64  `tfsdk:"max_length"`
+	AllowedCharacters   types.String `tfsdk:"allowed_characters"`
+	RemoveHyphens       types.Bool   `tfsdk:"remove_hyphens"`
+	MustStartWithLetter types.Bool   `tfsdk:"must_start_with_letter"`
+}
+
+func (d *resourceTypesDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_resource_types"
+}
+
+func (d *resourceTypesDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Lists the resource types supported by the naming functions, including the custom_resource_types of the provider, with their abbreviation, category and Azure naming constraints.",
+
+		Attributes: map[string]schema.Attribute{
+			"resource_types": schema.ListNestedAttribute{
+				Description: "The supported resource types, sorted by resource type.",
+				Computed:    true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"resource_type": schema.StringAttribute{
+							Description: "The resource type, as accepted by resource_name.",
+							Computed:    true,
+						},
+						"abbreviation": schema.StringAttribute{
+							Description: "The abbreviation used in the generated names.",
+							Computed:    true,
+						},
+						"category": schema.StringAttribute{
+							Description: fmt.Sprintf("The category of the resource type (e.g. Compute, Storage, Networking), %q for custom resource types.", customResourceTypesCategory),
+							Computed:    true,
+						},
+						"min_length": schema.Int64Attribute{
+							Description: "The minimum length of the generated names.",
+							Computed:    true,
+						},
+						"max_length": schema.Int64Attribute{
+							Description: "The maximum length of the generated names.",
+							Computed:    true,
+						},
+						"allowed_characters": schema.StringAttribute{
+							Description: "The characters Azure accepts in the generated names.",
+							Computed:    true,
+						},
+						"remove_hyphens": schema.BoolAttribute{
+							Description: "Whether hyphens are removed from the generated names.",
+							Computed:    true,
+						},
+						"must_start_with_letter": schema.BoolAttribute{
+							Description: "Whether the generated names must start with a letter.",
+							Computed:    true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Configure reads the resource types extended with the custom ones of the provider
+func (d *resourceTypesDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.abbreviations = providerData.resourceAbbreviations
+}
+
+func (d *resourceTypesDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	abbreviations := d.abbreviations
+	if abbreviations == nil {
+		abbreviations = getResourceAbbreviations()
+	}
+
+	resourceTypes := make([]string, 0, len(abbreviations))
+	for resourceType := range abbreviations {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	data := resourceTypesDataSourceModel{
+		ResourceTypes: make([]resourceTypeModel, 0, len(resourceTypes)),
+	}
+	for _, resourceType := range resourceTypes {
+		resourceAbbr := abbreviations[resourceType]
+		data.ResourceTypes = append(data.ResourceTypes, resourceTypeModel{
+			ResourceType:        types.StringValue(resourceType),
+			Abbreviation:        types.StringValue(resourceAbbr.abbreviation),
+			Category:            types.StringValue(resourceAbbr.category),
+			MinLength:           types.Int64Value(int64(resourceAbbr.rule.minLength)),
+			MaxLength:           types.Int64Value(int64(resourceAbbr.rule.maxLength)),
+			AllowedCharacters:   types.StringValue(resourceAbbr.rule.charset.description),
+			RemoveHyphens:       types.BoolValue(resourceAbbr.rule.removeHyphens),
+			MustStartWithLetter: types.BoolValue(resourceAbbr.rule.mustStartWithLetter),
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
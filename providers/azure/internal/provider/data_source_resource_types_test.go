@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+)
+
+func TestResourceTypesDataSource(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "dx" {
+  custom_resource_types = {
+    openai = { abbreviation = "oai", max_length = 64 }
+  }
+}
+
+data "dx_resource_types" "all" {}
+
+output "count" {
+  value = length(data.dx_resource_types.all.resource_types)
+}
+
+output "key_vault" {
+  value = one([for t in data.dx_resource_types.all.resource_types : t if t.resource_type == "key_vault"])
+}
+
+output "openai" {
+  value = one([for t in data.dx_resource_types.all.resource_types : t if t.resource_type == "openai"])
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("count", knownvalue.Int64Exact(int64(len(getResourceAbbreviations())+1))),
+					statecheck.ExpectKnownOutputValue("key_vault", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"resource_type":          knownvalue.StringExact("key_vault"),
+						"abbreviation":           knownvalue.StringExact("kv"),
+						"category":               knownvalue.StringExact("Security"),
+						"min_length":             knownvalue.Int64Exact(3),
+						"max_length":             knownvalue.Int64Exact(24),
+						"allowed_characters":     knownvalue.StringExact("lowercase letters, numbers and hyphens"),
+						"remove_hyphens":         knownvalue.Bool(false),
+						"must_start_with_letter": knownvalue.Bool(true),
+					})),
+					statecheck.ExpectKnownOutputValue("openai", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"resource_type":          knownvalue.StringExact("openai"),
+						"abbreviation":           knownvalue.StringExact("oai"),
+						"category":               knownvalue.StringExact("Custom"),
+						"min_length":             knownvalue.Int64Exact(1),
+						"max_length":             knownvalue.Int64Exact(64),
+						"allowed_characters":     knownvalue.StringExact("lowercase letters, numbers and hyphens"),
+						"remove_hyphens":         knownvalue.Bool(false),
+						"must_start_with_letter": knownvalue.Bool(false),
+					})),
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,243 @@
+// Implementation of the data source reporting the free address space of an Azure Virtual Network
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+
+	"github.com/apparentlymart/go-cidr/cidr"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = &virtualNetworkFreeSpaceDataSource{}
+
+func NewVirtualNetworkFreeSpaceDataSource() datasource.DataSource {
+	return &virtualNetworkFreeSpaceDataSource{}
+}
+
+// Data source definition
+type virtualNetworkFreeSpaceDataSource struct {
+	// reservations holds the blocks allocated by other runs, nil when not configured
+	reservations cidrReservationStore
+	// network reads the Virtual Networks, with the credential and cloud configured in the provider
+	network networkClient
+}
+
+// Data source model
+type virtualNetworkFreeSpaceDataSourceModel struct {
+	VirtualNetworkID             types.String `tfsdk:"virtual_network_id"`
+	PrefixLengths                types.Set    `tfsdk:"prefix_lengths"`
+	FreeCidrs                    types.List   `tfsdk:"free_cidrs"`
+	LargestAvailablePrefixLength types.Int64  `tfsdk:"largest_available_prefix_length"`
+	AvailableBlocks              types.Map    `tfsdk:"available_blocks"`
+}
+
+func (d *virtualNetworkFreeSpaceDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_virtual_network_free_space"
+}
+
+func (d *virtualNetworkFreeSpaceDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Reports the address space of an Azure Virtual Network not used by its subnets nor reserved by dx_available_subnet_cidr, without reserving anything.",
+
+		Attributes: map[string]schema.Attribute{
+			"virtual_network_id": schema.StringAttribute{
+				Description: "The Azure Resource ID of the Virtual Network.",
+				Required:    true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourcegroups/[^/]+/providers/microsoft\.network/virtualnetworks/[^/]+$`),
+						"must be a valid Azure VNet resource ID in the format /subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{vnetName}",
+					),
+				},
+			},
+			"prefix_lengths": schema.SetAttribute{
+				Description: "The IPv4 prefix lengths to count the free blocks of in available_blocks (e.g. [24, 27]).",
+				Optional:    true,
+				ElementType: types.Int64Type,
+				Validators: []validator.Set{
+					setvalidator.ValueInt64sAre(int64validator.Between(1, 29)), // Azure subnet limits
+				},
+			},
+			"free_cidrs": schema.ListAttribute{
+				Description: "The free ranges of the address space, as the fewest CIDR blocks covering them, in address space order.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"largest_available_prefix_length": schema.Int64Attribute{
+				Description: "The prefix length of the largest free IPv4 block, null when the IPv4 address space is full.",
+				Computed:    true,
+			},
+			"available_blocks": schema.MapAttribute{
+				Description: "The number of free IPv4 blocks of each of the prefix_lengths, keyed by prefix length.",
+				Computed:    true,
+				ElementType: types.Int64Type,
+			},
+		},
+	}
+}
+
+// Configure reads the reservation store and the network client configured in the provider
+func (d *virtualNetworkFreeSpaceDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Data Source Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	d.reservations = providerData.reservationStore
+	d.network = providerData.network
+}
+
+func (d *virtualNetworkFreeSpaceDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data virtualNetworkFreeSpaceDataSourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var prefixLengths []int64
+	if !data.PrefixLengths.IsNull() {
+		resp.Diagnostics.Append(data.PrefixLengths.ElementsAs(ctx, &prefixLengths, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	vnetID := data.VirtualNetworkID.ValueString()
+	parsedID, err := parseVNetID(vnetID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Virtual Network ID",
+			fmt.Sprintf("Cannot parse VNet ID '%s': %s", vnetID, err),
+		)
+		return
+	}
+
+	persisted, err := reservedCidrBlocks(ctx, d.reservations, vnetID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"CIDR Reservation Error",
+			fmt.Sprintf("Failed to list the reserved CIDR blocks: %s", err),
+		)
+		return
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, d.network, parsedID)
+	if err != nil {
+		resp.Diagnostics.Append(azureAPIErrorDiagnostic(err))
+		return
+	}
+
+	// Blocks handed out in this apply are not free either, but nothing is reserved here
+	allocations, unlock := subnetCidrAllocator.lock(vnetID)
+	used := append(layout.subnetBlocks(), allocations.reservedBlocks()...)
+	unlock()
+	used = append(used, persisted...)
+
+	free := freeCidrBlocks(layout.addressPrefixes, used)
+
+	freeCidrs := make([]string, 0, len(free))
+	for _, block := range free {
+		freeCidrs = append(freeCidrs, block.String())
+	}
+	var diags diag.Diagnostics
+	data.FreeCidrs, diags = types.ListValueFrom(ctx, types.StringType, freeCidrs)
+	resp.Diagnostics.Append(diags...)
+
+	data.LargestAvailablePrefixLength = types.Int64Null()
+	if prefixLen, ok := largestFreePrefixLength(free); ok {
+		data.LargestAvailablePrefixLength = types.Int64Value(int64(prefixLen))
+	}
+
+	availableBlocks := make(map[string]int64, len(prefixLengths))
+	for _, prefixLen := range prefixLengths {
+		availableBlocks[strconv.FormatInt(prefixLen, 10)] = freeBlockCount(free, int(prefixLen))
+	}
+	data.AvailableBlocks, diags = types.MapValueFrom(ctx, types.Int64Type, availableBlocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// freeCidrBlocks returns the parts of the address prefixes not overlapping the used blocks,
+// as the fewest aligned CIDR blocks covering them
+func freeCidrBlocks(addressPrefixes []*net.IPNet, used []*net.IPNet) []*net.IPNet {
+	var free []*net.IPNet
+	for _, addressPrefix := range addressPrefixes {
+		free = append(free, freeBlocksIn(addressPrefix, used)...)
+	}
+	return free
+}
+
+// freeBlocksIn splits a block in halves until each half is either free or fully used
+func freeBlocksIn(block *net.IPNet, used []*net.IPNet) []*net.IPNet {
+	if !overlapsAny(block, used) {
+		return []*net.IPNet{block}
+	}
+	prefixLen, bits := block.Mask.Size()
+	if prefixLen == bits || cidrWithinAddressSpace(block, used) {
+		return nil
+	}
+
+	lower, err := cidr.Subnet(block, 1, 0)
+	if err != nil {
+		return nil
+	}
+	upper, err := cidr.Subnet(block, 1, 1)
+	if err != nil {
+		return nil
+	}
+	return append(freeBlocksIn(lower, used), freeBlocksIn(upper, used)...)
+}
+
+// largestFreePrefixLength returns the prefix length of the largest free IPv4 block
+func largestFreePrefixLength(free []*net.IPNet) (int, bool) {
+	largest, found := 0, false
+	for _, block := range free {
+		if block.IP.To4() == nil {
+			continue
+		}
+		prefixLen, _ := block.Mask.Size()
+		if !found || prefixLen < largest {
+			largest, found = prefixLen, true
+		}
+	}
+	return largest, found
+}
+
+// freeBlockCount counts the IPv4 blocks of a prefix length fitting in the free blocks. Being
+// aligned, every free block of that size lies within exactly one of them.
+func freeBlockCount(free []*net.IPNet, prefixLen int) int64 {
+	var count int64
+	for _, block := range free {
+		if block.IP.To4() == nil {
+			continue
+		}
+		if blockPrefixLen, _ := block.Mask.Size(); blockPrefixLen <= prefixLen {
+			count += 1 << uint(prefixLen-blockPrefixLen)
+		}
+	}
+	return count
+}
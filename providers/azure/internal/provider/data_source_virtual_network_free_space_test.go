@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestFreeCidrBlocks(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := []*net.IPNet{mustParseCIDR(t, "10.0.0.0/24"), mustParseCIDR(t, "fd00:db8:deca::/48")}
+	used := []*net.IPNet{
+		mustParseCIDR(t, "10.0.0.0/27"),
+		mustParseCIDR(t, "10.0.0.64/26"),
+		mustParseCIDR(t, "fd00:db8:deca::/49"),
+	}
+
+	free := freeCidrBlocks(addressSpace, used)
+
+	var got []string
+	for _, block := range free {
+		got = append(got, block.String())
+	}
+	expected := []string{"10.0.0.32/27", "10.0.0.128/25", "fd00:db8:deca:8000::/49"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if prefixLen, ok := largestFreePrefixLength(free); !ok || prefixLen != 25 {
+		t.Errorf("expected a largest free prefix of /25, got /%d (%t)", prefixLen, ok)
+	}
+
+	for prefixLen, expected := range map[int]int64{24: 0, 25: 1, 26: 2, 27: 5, 29: 20} {
+		if got := freeBlockCount(free, prefixLen); got != expected {
+			t.Errorf("/%d: expected %d free blocks, got %d", prefixLen, expected, got)
+		}
+	}
+}
+
+func TestFreeCidrBlocks_Full(t *testing.T) {
+	t.Parallel()
+
+	free := freeCidrBlocks([]*net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}, []*net.IPNet{mustParseCIDR(t, "10.0.0.0/16")})
+	if len(free) != 0 {
+		t.Errorf("expected no free block, got %v", free)
+	}
+	if _, ok := largestFreePrefixLength(free); ok {
+		t.Error("expected no largest free prefix")
+	}
+}
+
+func TestVirtualNetworkFreeSpaceDataSource_InvalidPrefixLengths(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "dx_virtual_network_free_space" "test" {
+  virtual_network_id = %q
+  prefix_lengths     = [24, 30]
+}
+`, testVNetID),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`value must be between 1 and 29`),
+			},
+		},
+	})
+}
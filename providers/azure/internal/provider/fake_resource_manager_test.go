@@ -0,0 +1,320 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+)
+
+// fakeARMPageSize is the number of subnets per page, small so the pagination is exercised
+const fakeARMPageSize = 2
+
+// fakeARM is an in-process stand-in of the Resource Manager VirtualNetworks and Subnets APIs
+type fakeARM struct {
+	mu     sync.Mutex
+	vnets  map[string]*fakeVirtualNetwork
+	server *httptest.Server
+
+	// requests counts the requests received, retries included
+	requests int
+	// throttled is the number of next requests answered with 429, carrying the throttleHeader
+	throttled      int
+	throttleHeader [2]string
+	// denied answers every request with 403, like a role assignment missing the read permissions
+	denied bool
+	// concurrentTags are added to the VNet right before the next tags update, like another run would
+	concurrentTags map[string]string
+}
+
+type fakeVirtualNetwork struct {
+	id              string
+	addressPrefixes []string
+	subnets         []fakeSubnet
+	tags            map[string]*string
+	// version makes up the ETag, it changes on every write like in Azure
+	version int
+	// reads counts the reads of the VNet, pendingSubnets are added when it reaches their key
+	reads          int
+	pendingSubnets map[int]fakeSubnet
+}
+
+type fakeSubnet struct {
+	name            string
+	addressPrefixes []string
+}
+
+// fakeCredential hands out tokens the fake never checks
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "fake", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func newFakeARM(t *testing.T) *fakeARM {
+	t.Helper()
+
+	fake := &fakeARM{vnets: map[string]*fakeVirtualNetwork{}}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+// network returns the Azure SDK clients of the provider, talking to the fake
+func (f *fakeARM) network(t *testing.T) networkClient {
+	t.Helper()
+	return f.networkWithRetry(t, retryConfig{})
+}
+
+// networkWithRetry returns the Azure SDK clients of the provider with a retry policy, talking to the fake
+func (f *fakeARM) networkWithRetry(t *testing.T, retry retryConfig) networkClient {
+	t.Helper()
+
+	cloudConfig, err := azureCloudConfiguration(types.StringNull(), types.StringValue(f.server.URL))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return &azureClients{credential: fakeCredential{}, options: armClientOptions(cloudConfig, retry)}
+}
+
+// providerFactories returns provider factories whose resources and data sources talk to the fake
+func (f *fakeARM) providerFactories(t *testing.T) map[string]func() (tfprotov6.ProviderServer, error) {
+	network := f.network(t)
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"dx": providerserver.NewProtocol6WithError(&dxProvider{Version: "test", network: network}),
+	}
+}
+
+// addVirtualNetwork creates a VNet in the fake, returning its ID
+func (f *fakeARM) addVirtualNetwork(name string, addressPrefixes ...string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/" + name
+	f.vnets[strings.ToLower(id)] = &fakeVirtualNetwork{id: id, addressPrefixes: addressPrefixes, tags: map[string]*string{}}
+	return id
+}
+
+func (f *fakeARM) addSubnet(vnetID, name string, addressPrefixes ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vnet := f.vnets[strings.ToLower(vnetID)]
+	vnet.subnets = append(vnet.subnets, fakeSubnet{name: name, addressPrefixes: addressPrefixes})
+	vnet.version++
+}
+
+// addSubnetOnRead adds a subnet right before the given read of the VNet, counting from 1, as if
+// another run created it between two steps of the provider
+func (f *fakeARM) addSubnetOnRead(vnetID string, read int, name string, addressPrefixes ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	vnet := f.vnets[strings.ToLower(vnetID)]
+	if vnet.pendingSubnets == nil {
+		vnet.pendingSubnets = map[int]fakeSubnet{}
+	}
+	vnet.pendingSubnets[read] = fakeSubnet{name: name, addressPrefixes: addressPrefixes}
+}
+
+// writeConcurrently adds tags to the VNet right before the next write of the provider, as if another
+// run wrote them between the read and the write of the provider
+func (f *fakeARM) writeConcurrently(tags map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.concurrentTags = tags
+}
+
+func (f *fakeARM) deleteVirtualNetwork(vnetID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.vnets, strings.ToLower(vnetID))
+}
+
+// throttle answers the next requests with 429, setting the header telling when to retry,
+// e.g. Retry-After in seconds or retry-after-ms
+func (f *fakeARM) throttle(requests int, header, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.throttled = requests
+	f.throttleHeader = [2]string{header, value}
+}
+
+func (f *fakeARM) deny() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.denied = true
+}
+
+func (f *fakeARM) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.requests
+}
+
+// tags returns a copy of the VNet tags
+func (f *fakeARM) tags(vnetID string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tags := map[string]string{}
+	for name, value := range f.vnets[strings.ToLower(vnetID)].tags {
+		tags[name] = *value
+	}
+	return tags
+}
+
+func (f *fakeARM) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	if f.denied {
+		writeARMError(w, http.StatusForbidden, "AuthorizationFailed",
+			fmt.Sprintf("The client does not have authorization to perform action 'Microsoft.Network/virtualNetworks/read' over scope '%s'", r.URL.Path))
+		return
+	}
+	if f.throttled > 0 {
+		f.throttled--
+		w.Header().Set(f.throttleHeader[0], f.throttleHeader[1])
+		writeARMError(w, http.StatusTooManyRequests, "TooManyRequests", "The request is being throttled")
+		return
+	}
+
+	// /subscriptions/{s}/resourceGroups/{rg}/providers/Microsoft.Network/virtualNetworks/{vnet}[/subnets[/{subnet}]]
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) < 8 {
+		writeARMError(w, http.StatusNotFound, "InvalidResourceType", "unsupported path "+r.URL.Path)
+		return
+	}
+	vnet, ok := f.vnets[strings.ToLower("/"+strings.Join(segments[:8], "/"))]
+	if !ok {
+		writeARMError(w, http.StatusNotFound, "ResourceNotFound", fmt.Sprintf("Virtual Network '%s' not found", segments[7]))
+		return
+	}
+
+	switch {
+	case len(segments) == 8 && r.Method == http.MethodGet:
+		vnet.reads++
+		if subnet, ok := vnet.pendingSubnets[vnet.reads]; ok {
+			vnet.subnets = append(vnet.subnets, subnet)
+			vnet.version++
+		}
+		writeARMJSON(w, vnet.resource())
+	case len(segments) == 8 && r.Method == http.MethodPatch:
+		var body struct {
+			Tags map[string]*string `json:"tags"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeARMError(w, http.StatusBadRequest, "InvalidRequestContent", err.Error())
+			return
+		}
+		if f.concurrentTags != nil {
+			for name, value := range f.concurrentTags {
+				vnet.tags[name] = &value
+			}
+			vnet.version++
+			f.concurrentTags = nil
+		}
+		if match := r.Header.Get("If-Match"); match != "" && match != vnet.etag() {
+			writeARMError(w, http.StatusPreconditionFailed, "PreconditionFailed",
+				fmt.Sprintf("Operation failed because the If-Match ETag %s does not match the current ETag %s", match, vnet.etag()))
+			return
+		}
+		vnet.tags = body.Tags
+		if vnet.tags == nil {
+			vnet.tags = map[string]*string{}
+		}
+		vnet.version++
+		writeARMJSON(w, vnet.resource())
+	case len(segments) == 9 && segments[8] == "subnets" && r.Method == http.MethodGet:
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start, end := page*fakeARMPageSize, (page+1)*fakeARMPageSize
+		if end > len(vnet.subnets) {
+			end = len(vnet.subnets)
+		}
+		values := []interface{}{}
+		for _, subnet := range vnet.subnets[min(start, end):end] {
+			values = append(values, vnet.subnetResource(subnet))
+		}
+		list := map[string]interface{}{"value": values}
+		if end < len(vnet.subnets) {
+			next := *r.URL
+			query := next.Query()
+			query.Set("page", strconv.Itoa(page+1))
+			next.RawQuery = query.Encode()
+			list["nextLink"] = f.server.URL + next.RequestURI()
+		}
+		writeARMJSON(w, list)
+	case len(segments) == 10 && segments[8] == "subnets" && r.Method == http.MethodGet:
+		for _, subnet := range vnet.subnets {
+			if strings.EqualFold(subnet.name, segments[9]) {
+				writeARMJSON(w, vnet.subnetResource(subnet))
+				return
+			}
+		}
+		writeARMError(w, http.StatusNotFound, "NotFound", fmt.Sprintf("Subnet '%s' not found", segments[9]))
+	default:
+		writeARMError(w, http.StatusMethodNotAllowed, "UnsupportedOperation", r.Method+" "+r.URL.Path)
+	}
+}
+
+func (v *fakeVirtualNetwork) etag() string {
+	return fmt.Sprintf(`W/"%d"`, v.version)
+}
+
+func (v *fakeVirtualNetwork) resource() map[string]interface{} {
+	return map[string]interface{}{
+		"id":   v.id,
+		"name": v.id[strings.LastIndex(v.id, "/")+1:],
+		"etag": v.etag(),
+		"tags": v.tags,
+		"properties": map[string]interface{}{
+			"addressSpace": map[string]interface{}{"addressPrefixes": v.addressPrefixes},
+		},
+	}
+}
+
+// subnetResource returns single-prefix subnets with addressPrefix, like Azure does
+func (v *fakeVirtualNetwork) subnetResource(subnet fakeSubnet) map[string]interface{} {
+	properties := map[string]interface{}{"addressPrefixes": subnet.addressPrefixes}
+	if len(subnet.addressPrefixes) == 1 {
+		properties = map[string]interface{}{"addressPrefix": subnet.addressPrefixes[0]}
+	}
+	return map[string]interface{}{
+		"id":         v.id + "/subnets/" + subnet.name,
+		"name":       subnet.name,
+		"properties": properties,
+	}
+}
+
+func writeARMJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeARMError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
@@ -3,53 +3,11 @@ package provider
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/function"
 )
 
-// locationShortToLong is the single source of truth mapping short location codes
-// to their full Azure region names. locationLongToShort is derived from this map.
-var locationShortToLong = map[string]string{
-	"gwc": "germanycentral",
-	"itn": "italynorth",
-	"neu": "northeurope",
-	"spc": "spaincentral",
-	"swc": "swedencentral",
-	"weu": "westeurope",
-}
-
-// locationLongToShort is derived from locationShortToLong at init time.
-var locationLongToShort map[string]string
-
-func init() {
-	locationLongToShort = make(map[string]string, len(locationShortToLong))
-	for short, long := range locationShortToLong {
-		locationLongToShort[long] = short
-	}
-}
-
-// validShortLocations returns a stable, sorted comma-separated list of valid short location codes.
-func validShortLocations() string {
-	keys := make([]string, 0, len(locationShortToLong))
-	for k := range locationShortToLong {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	return strings.Join(keys, ", ")
-}
-
-// validLongLocations returns a stable, sorted comma-separated list of valid long location names.
-func validLongLocations() string {
-	keys := make([]string, 0, len(locationLongToShort))
-	for k := range locationLongToShort {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-	return strings.Join(keys, ", ")
-}
-
 // --- convert_location_to_long_format ---
 
 var _ function.Function = &convertLocationToLongFormatFunction{}
@@ -138,3 +138,54 @@ output "test" {
 		},
 	})
 }
+
+// --- provider location validator ---
+
+// providerLocationConfig declares a resource so that Terraform validates the provider block.
+const providerLocationConfig = `
+provider "dx" {
+  location = %q
+}
+
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Network/virtualNetworks/vnet"
+  prefix_length      = 24
+}
+`
+
+func TestProviderLocation(t *testing.T) {
+	t.Parallel()
+
+	// The provider schema accepts every location known to the location registry, in any case like resource_name
+	for _, location := range []string{"swc", "spaincentral", "SWC", "SpainCentral"} {
+		location := location
+		t.Run(location, func(t *testing.T) {
+			t.Parallel()
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config:             fmt.Sprintf(providerLocationConfig, location),
+						PlanOnly:           true,
+						ExpectNonEmptyPlan: true,
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestProviderLocation_Invalid(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      fmt.Sprintf(providerLocationConfig, "eastus"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Value Match`),
+			},
+		},
+	})
+}
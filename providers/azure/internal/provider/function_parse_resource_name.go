@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &parseResourceNameFunction{}
+
+type parseResourceNameFunction struct{}
+
+func NewParseResourceNameFunction() function.Function {
+	return &parseResourceNameFunction{}
+}
+
+// parsedResourceName is the object returned by parse_resource_name
+type parsedResourceName struct {
+	Prefix         string       `tfsdk:"prefix"`
+	Environment    string       `tfsdk:"environment"`
+	Location       string       `tfsdk:"location"`
+	Domain         types.String `tfsdk:"domain"`
+	Name           types.String `tfsdk:"name"`
+	ResourceType   string       `tfsdk:"resource_type"`
+	Abbreviation   string       `tfsdk:"abbreviation"`
+	InstanceNumber int64        `tfsdk:"instance_number"`
+}
+
+func (f *parseResourceNameFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_resource_name"
+}
+
+func (f *parseResourceNameFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Decompose an Azure dx resource name into its parts",
+		Description: "Given a name following the Azure dx resources naming convention, returns an object with prefix, environment, location, domain, name, resource_type, abbreviation and instance_number. " +
+			"Fails when the name is not dx-compliant. When a single segment sits between location and abbreviation it is returned as name, and storage account names (without hyphens) never report a domain.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:        "resource_name",
+				Description: "The resource name to decompose (e.g. \"dx-d-itn-test-example-snet-01\").",
+			},
+		},
+		VariadicParameter: customResourceTypesParameter(),
+		Return: function.ObjectReturn{
+			AttributeTypes: map[string]attr.Type{
+				"prefix":          types.StringType,
+				"environment":     types.StringType,
+				"location":        types.StringType,
+				"domain":          types.StringType,
+				"name":            types.StringType,
+				"resource_type":   types.StringType,
+				"abbreviation":    types.StringType,
+				"instance_number": types.Int64Type,
+			},
+		},
+	}
+}
+
+func (f *parseResourceNameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var resourceName string
+	var customResourceTypes []types.Dynamic
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &resourceName, &customResourceTypes))
+	if resp.Error != nil {
+		return
+	}
+
+	abbreviations, err := customResourceTypesFromArguments(customResourceTypes)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	parsed, err := parseResourceName(resourceName, abbreviations)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, parsed))
+}
+
+// parseResourceName decomposes a dx resource name. The result is composed again and
+// compared with the input, so only names resource_name could have produced are accepted.
+func parseResourceName(resourceName string, abbreviations map[string]resourceAbbreviation) (*parsedResourceName, *function.FuncError) {
+	normalized := strings.ToLower(strings.TrimSpace(resourceName))
+
+	var (
+		parsed *parsedResourceName
+		reason string
+	)
+	if strings.Contains(normalized, "-") {
+		parsed, reason = parseHyphenatedResourceName(normalized, abbreviations)
+	} else {
+		parsed, reason = parseCompactResourceName(normalized, abbreviations)
+	}
+	if parsed == nil {
+		return nil, invalidParsedName(resourceName, reason)
+	}
+
+	if err := validatePrefix(parsed.Prefix); err != nil {
+		return nil, invalidParsedName(resourceName, "prefix must be between 2 and 4 characters long")
+	}
+
+	resourceAbbr := abbreviations[parsed.ResourceType]
+	recomposed := composeResourceName(parsed.Prefix, parsed.Environment, parsed.Location,
+		parsed.Domain.ValueString(), parsed.Name.ValueString(), resourceAbbr, int(parsed.InstanceNumber))
+	if recomposed != normalized {
+		return nil, invalidParsedName(resourceName, fmt.Sprintf("expected '%s' for the parsed values", recomposed))
+	}
+
+	if err := validateNamingRule(parsed.ResourceType, normalized, resourceAbbr.rule); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// parseHyphenatedResourceName parses names in the prefix-environment-location[-domain][-name]-abbreviation-instance format
+func parseHyphenatedResourceName(resourceName string, abbreviations map[string]resourceAbbreviation) (*parsedResourceName, string) {
+	parts := strings.Split(resourceName, "-")
+	if len(parts) < 5 {
+		return nil, "expected at least prefix, environment, location, abbreviation and instance number"
+	}
+
+	instance, err := parseInstanceNumber(parts[len(parts)-1])
+	if err != nil {
+		return nil, "the last segment must be an instance number between 01 and 99"
+	}
+
+	if validateEnvironment(parts[1]) != nil {
+		return nil, "environment must be 'd', 'u' or 'p'"
+	}
+
+	if _, ok := locationShortToLong[parts[2]]; !ok {
+		return nil, fmt.Sprintf("location must be one of: %s", validShortLocations())
+	}
+
+	// The longest trailing run of segments matching an abbreviation wins,
+	// e.g. "app-pep" (app_private_endpoint) over "pep" (private_endpoint).
+	middle := parts[3 : len(parts)-1]
+	for start := 0; start < len(middle); start++ {
+		resourceType, ok := findResourceTypeByAbbreviation(strings.Join(middle[start:], "-"), abbreviations, false)
+		if !ok {
+			continue
+		}
+
+		parsed := &parsedResourceName{
+			Prefix:         parts[0],
+			Environment:    parts[1],
+			Location:       parts[2],
+			Domain:         types.StringNull(),
+			Name:           types.StringNull(),
+			ResourceType:   resourceType,
+			Abbreviation:   abbreviations[resourceType].abbreviation,
+			InstanceNumber: int64(instance),
+		}
+
+		// A single remaining segment is the name, two or more are domain followed by name
+		switch rest := middle[:start]; len(rest) {
+		case 0:
+		case 1:
+			parsed.Name = types.StringValue(rest[0])
+		default:
+			parsed.Domain = types.StringValue(rest[0])
+			parsed.Name = types.StringValue(strings.Join(rest[1:], "-"))
+		}
+
+		return parsed, ""
+	}
+
+	return nil, "no known resource type abbreviation found"
+}
+
+// parseCompactResourceName parses names of resource types that don't accept hyphens (e.g. storage accounts)
+func parseCompactResourceName(resourceName string, abbreviations map[string]resourceAbbreviation) (*parsedResourceName, string) {
+	if len(resourceName) < 2 {
+		return nil, "name is too short"
+	}
+
+	instance, err := parseInstanceNumber(resourceName[len(resourceName)-2:])
+	if err != nil {
+		return nil, "the name must end with an instance number between 01 and 99"
+	}
+	body := resourceName[:len(resourceName)-2]
+
+	// The prefix length is not delimited: try 2 to 4 characters followed by a valid
+	// environment and a known short location code
+	for prefixLength := 2; prefixLength <= 4; prefixLength++ {
+		if len(body) < prefixLength+4 {
+			break
+		}
+
+		environment := body[prefixLength : prefixLength+1]
+		location := body[prefixLength+1 : prefixLength+4]
+		if validateEnvironment(environment) != nil {
+			continue
+		}
+		if _, ok := locationShortToLong[location]; !ok {
+			continue
+		}
+
+		middle := body[prefixLength+4:]
+		for start := 0; start < len(middle); start++ {
+			resourceType, ok := findResourceTypeByAbbreviation(middle[start:], abbreviations, true)
+			if !ok {
+				continue
+			}
+
+			parsed := &parsedResourceName{
+				Prefix:         body[:prefixLength],
+				Environment:    environment,
+				Location:       location,
+				Domain:         types.StringNull(),
+				Name:           types.StringNull(),
+				ResourceType:   resourceType,
+				Abbreviation:   abbreviations[resourceType].abbreviation,
+				InstanceNumber: int64(instance),
+			}
+			if start > 0 {
+				parsed.Name = types.StringValue(middle[:start])
+			}
+
+			return parsed, ""
+		}
+	}
+
+	return nil, "expected a name with hyphens or a storage account name with a known abbreviation"
+}
+
+// findResourceTypeByAbbreviation looks up the resource type of an abbreviation.
+// When compact is true only resource types whose names are stored without hyphens match.
+func findResourceTypeByAbbreviation(abbreviation string, abbreviations map[string]resourceAbbreviation, compact bool) (string, bool) {
+	for resourceType, resourceAbbr := range abbreviations {
+		if resourceAbbr.rule.removeHyphens != compact {
+			continue
+		}
+		candidate := resourceAbbr.abbreviation
+		if compact {
+			candidate = strings.ReplaceAll(candidate, "-", "")
+		}
+		if candidate == abbreviation {
+			return resourceType, true
+		}
+	}
+	return "", false
+}
+
+func invalidParsedName(resourceName, reason string) *function.FuncError {
+	return function.NewFuncError(fmt.Sprintf("InvalidResourceName: '%s' is not a dx-compliant resource name: %s", resourceName, reason))
+}
@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestParseResourceNameFunction(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		input    string
+		expected map[string]knownvalue.Check
+	}{
+		{
+			input: "dx-d-itn-test-example-snet-01",
+			expected: map[string]knownvalue.Check{
+				"prefix":          knownvalue.StringExact("dx"),
+				"environment":     knownvalue.StringExact("d"),
+				"location":        knownvalue.StringExact("itn"),
+				"domain":          knownvalue.StringExact("test"),
+				"name":            knownvalue.StringExact("example"),
+				"resource_type":   knownvalue.StringExact("subnet"),
+				"abbreviation":    knownvalue.StringExact("snet"),
+				"instance_number": knownvalue.Int64Exact(1),
+			},
+		},
+		{
+			input: "dx-d-weu-vm-01",
+			expected: map[string]knownvalue.Check{
+				"prefix":          knownvalue.StringExact("dx"),
+				"environment":     knownvalue.StringExact("d"),
+				"location":        knownvalue.StringExact("weu"),
+				"domain":          knownvalue.Null(),
+				"name":            knownvalue.Null(),
+				"resource_type":   knownvalue.StringExact("virtual_machine"),
+				"abbreviation":    knownvalue.StringExact("vm"),
+				"instance_number": knownvalue.Int64Exact(1),
+			},
+		},
+		{
+			input: "test-p-itn-cache-psql-pep-replica-12",
+			expected: map[string]knownvalue.Check{
+				"prefix":          knownvalue.StringExact("test"),
+				"environment":     knownvalue.StringExact("p"),
+				"location":        knownvalue.StringExact("itn"),
+				"domain":          knownvalue.Null(),
+				"name":            knownvalue.StringExact("cache"),
+				"resource_type":   knownvalue.StringExact("postgre_replica_private_endpoint"),
+				"abbreviation":    knownvalue.StringExact("psql-pep-replica"),
+				"instance_number": knownvalue.Int64Exact(12),
+			},
+		},
+		{
+			input: "dxpweuexamplest05",
+			expected: map[string]knownvalue.Check{
+				"prefix":          knownvalue.StringExact("dx"),
+				"environment":     knownvalue.StringExact("p"),
+				"location":        knownvalue.StringExact("weu"),
+				"domain":          knownvalue.Null(),
+				"name":            knownvalue.StringExact("example"),
+				"resource_type":   knownvalue.StringExact("storage_account"),
+				"abbreviation":    knownvalue.StringExact("st"),
+				"instance_number": knownvalue.Int64Exact(5),
+			},
+		},
+		{
+			input: "dxuitnmyfunctionstfn02",
+			expected: map[string]knownvalue.Check{
+				"prefix":          knownvalue.StringExact("dx"),
+				"environment":     knownvalue.StringExact("u"),
+				"location":        knownvalue.StringExact("itn"),
+				"domain":          knownvalue.Null(),
+				"name":            knownvalue.StringExact("myfunction"),
+				"resource_type":   knownvalue.StringExact("function_storage_account"),
+				"abbreviation":    knownvalue.StringExact("stfn"),
+				"instance_number": knownvalue.Int64Exact(2),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+			resource.UnitTest(t, resource.TestCase{
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+output "test" {
+  value = provider::dx::parse_resource_name(%q)
+}
+`, tc.input),
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(tc.expected)),
+						},
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestParseResourceNameFunction_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+locals {
+  name = provider::dx::resource_name({
+    prefix          = "dx",
+    environment     = "p",
+    location        = "italynorth",
+    domain          = "payments",
+    name            = "processor",
+    resource_type   = "function_app",
+    instance_number = 3,
+  })
+}
+
+output "test" {
+  value = provider::dx::parse_resource_name(local.name).resource_type
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact("function_app")),
+				},
+			},
+		},
+	})
+}
+
+func TestParseResourceNameFunction_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []string{
+		"my-random-name",
+		"dx-d-usa-example-vm-01",
+		"dx-x-itn-example-vm-01",
+		"dx-d-itn-example-vm-1",
+		"dx-d-itn-example-unknown-01",
+		"dx-d-itn-example-st-01",
+		"dxditnexample01",
+	}
+
+	for _, input := range cases {
+		input := input
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			resource.UnitTest(t, resource.TestCase{
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+output "test" {
+  value = provider::dx::parse_resource_name(%q)
+}
+`, input),
+						ExpectError: regexp.MustCompile(`InvalidResourceName`),
+					},
+				},
+			})
+		})
+	}
+}
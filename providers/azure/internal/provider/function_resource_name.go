@@ -2,7 +2,10 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -27,136 +30,196 @@ func (f *resourceNameFunction) Definition(ctx context.Context, req function.Defi
 		Parameters: []function.Parameter{
 			function.MapParameter{
 				Name:           "configuration",
-				Description:    "A map containing the following keys: prefix, environment (or env_short), location, domain (Optional), name (or app_name - Optional), resource_type and instance_number.",
+				Description:    "A map containing the following keys: prefix, environment (or env_short), location, domain (Optional), name (or app_name - Optional), resource_type, instance_number and truncate (Optional).",
 				ElementType:    types.StringType,
 				AllowNullValue: true,
 			},
 		},
-		Return: function.StringReturn{},
+		VariadicParameter: customResourceTypesParameter(),
+		Return:            function.StringReturn{},
 	}
 }
 
 // getResourceAbbreviations returns the mapping of resource types to their abbreviations
-func getResourceAbbreviations() map[string]string {
-	return map[string]string{
-		// Compute
-		"virtual_machine":           "vm",
-		"container_app_job":         "caj",
-		"container_app":             "ca",
-		"container_app_environment": "cae",
-		"container_instance":        "ci",
-
-		// Storage
-		"storage_account":                  "st",
-		"blob_storage":                     "blob",
-		"queue_storage":                    "queue",
-		"table_storage":                    "table",
-		"file_storage":                     "file",
-		"function_storage_account":         "stfn",
-		"customer_key_storage_account":     "stcmk",
-		"durable_function_storage_account": "stfd",
-
-		// Networking
-		"api_management":                            "apim",
-		"api_management_autoscale":                  "apim-as",
-		"virtual_network":                           "vnet",
-		"network_security_group":                    "nsg",
-		"apim_network_security_group":               "apim-nsg",
-		"app_gateway":                               "agw",
-		"cdn_frontdoor_profile":                     "afd",
-		"cdn_frontdoor_endpoint":                    "fde",
-		"cdn_frontdoor_origin_group":                "fdog",
-		"cdn_frontdoor_origin":                      "fdo",
-		"cdn_frontdoor_route":                       "cdnr",
-		"nat_gateway":                               "ng",
-		"postgre_endpoint":                          "psql-ep",
-		"dns_forwarding_ruleset":                    "dnsfrs",
-		"dns_private_resolver":                      "dnspr",
-		"dns_private_resolver_inbound_endpoint":     "in",
-		"dns_private_resolver_outbound_endpoint":    "out",
-		"dns_private_resolver_virtual_network_link": "dnsprvnetlink",
-		"virtual_network_gateway":                   "vgw",
-		"local_network_gateway":                     "lgw",
-		"virtual_network_gateway_connection":        "vgwcn",
-
-		// Private Endpoints
-		"private_endpoint":                   "pep",
-		"cosmos_private_endpoint":            "cosno-pep",
-		"postgre_private_endpoint":           "psql-pep",
-		"postgre_replica_private_endpoint":   "psql-pep-replica",
-		"app_private_endpoint":               "app-pep",
-		"app_slot_private_endpoint":          "staging-app-pep",
-		"function_private_endpoint":          "func-pep",
-		"function_slot_private_endpoint":     "staging-func-pep",
-		"blob_private_endpoint":              "blob-pep",
-		"function_blob_private_endpoint":     "func-blob-pep",
-		"dfunction_blob_private_endpoint":    "dfunc-blob-pep",
-		"queue_private_endpoint":             "queue-pep",
-		"function_queue_private_endpoint":    "func-queue-pep",
-		"dfunction_queue_private_endpoint":   "dfunc-queue-pep",
-		"file_private_endpoint":              "file-pep",
-		"function_file_private_endpoint":     "func-file-pep",
-		"dfunction_file_private_endpoint":    "dfunc-file-pep",
-		"table_private_endpoint":             "table-pep",
-		"function_table_private_endpoint":    "func-table-pep",
-		"dfunction_table_private_endpoint":   "dfunc-table-pep",
-		"eventhub_private_endpoint":          "evhns-pep",
-		"container_app_private_endpoint":     "cae-pep",
-		"key_vault_private_endpoint":         "kv-pep",
-		"servicebus_private_endpoint":        "sbns-pep",
-		"apim_private_endpoint":              "apim-pep",
-		"app_configuration_private_endpoint": "appcs-pep",
-		"managed_redis_private_endpoint":     "amr-pep",
-
-		// Public IPs
-		"public_ip": "pip",
-
-		// Subnets
-		"subnet":                    "snet",
-		"app_subnet":                "app-snet",
-		"apim_subnet":               "apim-snet",
-		"function_subnet":           "func-snet",
-		"container_app_subnet":      "cae-snet",
-		"container_instance_subnet": "ci-snet",
-		"private_endpoint_subnet":   "pep-snet",
-
-		// Databases
-		"cosmos_db_nosql":              "cosno",
-		"customer_key_cosmos_db_nosql": "cosno-cmk",
-		"postgresql":                   "psql",
-		"postgresql_replica":           "psql-replica",
-		"managed_redis":                "amr",
-		"redis_cache":                  "redis",
-		"mysql":                        "mysql",
-
-		// Integration
-		"eventhub_namespace":   "evhns",
-		"servicebus_namespace": "sbns",
-		"function_app":         "func",
-		"app_service":          "app",
-		"app_service_plan":     "asp",
-		"static_web_app":       "stapp",
-		"api_center":           "apic",
-
-		// Security
-		"key_vault":        "kv",
-		"managed_identity": "id",
-
-		// Monitoring
-		"application_insights":           "appi",
-		"log_analytics":                  "log",
-		"cdn_monitor_diagnostic_setting": "cdnp",
-		"monitor_alert_sbns_active":      "sbns-act-ma",
-		"monitor_alert_sbns_dlq":         "sbns-dlq-ma",
-
-		// Miscellaneous
-		"resource_group":    "rg",
-		"ai_search":         "srch",
-		"load_testing":      "lt",
-		"app_configuration": "appcs",
+// and the Azure naming rules the generated names must satisfy
+func getResourceAbbreviations() map[string]resourceAbbreviation {
+	abbreviations := make(map[string]resourceAbbreviation)
+	for category, resourceTypes := range getResourceAbbreviationsByCategory() {
+		for resourceType, resourceAbbr := range resourceTypes {
+			resourceAbbr.category = category
+			abbreviations[resourceType] = resourceAbbr
+		}
+	}
+	return abbreviations
+}
+
+// getResourceAbbreviationsByCategory returns the supported resource types grouped by category
+func getResourceAbbreviationsByCategory() map[string]map[string]resourceAbbreviation {
+	return map[string]map[string]resourceAbbreviation{
+		"Compute": {
+			"virtual_machine":           {abbreviation: "vm", rule: ruleVirtualMachine},
+			"container_app_job":         {abbreviation: "caj", rule: ruleContainerApp},
+			"container_app":             {abbreviation: "ca", rule: ruleContainerApp},
+			"container_app_environment": {abbreviation: "cae", rule: ruleContainerAppEnvironment},
+			"container_instance":        {abbreviation: "ci", rule: ruleContainerInstance},
+		},
+
+		"Storage": {
+			"storage_account":                  {abbreviation: "st", rule: ruleStorageAccount},
+			"blob_storage":                     {abbreviation: "blob", rule: ruleStorageChild},
+			"queue_storage":                    {abbreviation: "queue", rule: ruleStorageChild},
+			"table_storage":                    {abbreviation: "table", rule: ruleStorageTable},
+			"file_storage":                     {abbreviation: "file", rule: ruleStorageChild},
+			"function_storage_account":         {abbreviation: "stfn", rule: ruleStorageAccount},
+			"customer_key_storage_account":     {abbreviation: "stcmk", rule: ruleStorageAccount},
+			"durable_function_storage_account": {abbreviation: "stfd", rule: ruleStorageAccount},
+		},
+
+		"Networking": {
+			"api_management":                            {abbreviation: "apim", rule: ruleAPIManagement},
+			"api_management_autoscale":                  {abbreviation: "apim-as", rule: ruleMonitor},
+			"virtual_network":                           {abbreviation: "vnet", rule: ruleVirtualNetwork},
+			"network_security_group":                    {abbreviation: "nsg", rule: ruleNetwork},
+			"apim_network_security_group":               {abbreviation: "apim-nsg", rule: ruleNetwork},
+			"app_gateway":                               {abbreviation: "agw", rule: ruleNetwork},
+			"cdn_frontdoor_profile":                     {abbreviation: "afd", rule: ruleFrontDoorProfile},
+			"cdn_frontdoor_endpoint":                    {abbreviation: "fde", rule: ruleFrontDoorEndpoint},
+			"cdn_frontdoor_origin_group":                {abbreviation: "fdog", rule: ruleFrontDoorChild},
+			"cdn_frontdoor_origin":                      {abbreviation: "fdo", rule: ruleFrontDoorChild},
+			"cdn_frontdoor_route":                       {abbreviation: "cdnr", rule: ruleFrontDoorChild},
+			"nat_gateway":                               {abbreviation: "ng", rule: ruleNetwork},
+			"postgre_endpoint":                          {abbreviation: "psql-ep", rule: ruleDatabaseServer},
+			"dns_forwarding_ruleset":                    {abbreviation: "dnsfrs", rule: ruleNetwork},
+			"dns_private_resolver":                      {abbreviation: "dnspr", rule: ruleNetwork},
+			"dns_private_resolver_inbound_endpoint":     {abbreviation: "in", rule: ruleNetwork},
+			"dns_private_resolver_outbound_endpoint":    {abbreviation: "out", rule: ruleNetwork},
+			"dns_private_resolver_virtual_network_link": {abbreviation: "dnsprvnetlink", rule: ruleNetwork},
+			"virtual_network_gateway":                   {abbreviation: "vgw", rule: ruleNetwork},
+			"local_network_gateway":                     {abbreviation: "lgw", rule: ruleNetwork},
+			"virtual_network_gateway_connection":        {abbreviation: "vgwcn", rule: ruleNetwork},
+		},
+
+		"Private Endpoints": {
+			"private_endpoint":                   {abbreviation: "pep", rule: rulePrivateEndpoint},
+			"cosmos_private_endpoint":            {abbreviation: "cosno-pep", rule: rulePrivateEndpoint},
+			"postgre_private_endpoint":           {abbreviation: "psql-pep", rule: rulePrivateEndpoint},
+			"postgre_replica_private_endpoint":   {abbreviation: "psql-pep-replica", rule: rulePrivateEndpoint},
+			"app_private_endpoint":               {abbreviation: "app-pep", rule: rulePrivateEndpoint},
+			"app_slot_private_endpoint":          {abbreviation: "staging-app-pep", rule: rulePrivateEndpoint},
+			"function_private_endpoint":          {abbreviation: "func-pep", rule: rulePrivateEndpoint},
+			"function_slot_private_endpoint":     {abbreviation: "staging-func-pep", rule: rulePrivateEndpoint},
+			"blob_private_endpoint":              {abbreviation: "blob-pep", rule: rulePrivateEndpoint},
+			"function_blob_private_endpoint":     {abbreviation: "func-blob-pep", rule: rulePrivateEndpoint},
+			"dfunction_blob_private_endpoint":    {abbreviation: "dfunc-blob-pep", rule: rulePrivateEndpoint},
+			"queue_private_endpoint":             {abbreviation: "queue-pep", rule: rulePrivateEndpoint},
+			"function_queue_private_endpoint":    {abbreviation: "func-queue-pep", rule: rulePrivateEndpoint},
+			"dfunction_queue_private_endpoint":   {abbreviation: "dfunc-queue-pep", rule: rulePrivateEndpoint},
+			"file_private_endpoint":              {abbreviation: "file-pep", rule: rulePrivateEndpoint},
+			"function_file_private_endpoint":     {abbreviation: "func-file-pep", rule: rulePrivateEndpoint},
+			"dfunction_file_private_endpoint":    {abbreviation: "dfunc-file-pep", rule: rulePrivateEndpoint},
+			"table_private_endpoint":             {abbreviation: "table-pep", rule: rulePrivateEndpoint},
+			"function_table_private_endpoint":    {abbreviation: "func-table-pep", rule: rulePrivateEndpoint},
+			"dfunction_table_private_endpoint":   {abbreviation: "dfunc-table-pep", rule: rulePrivateEndpoint},
+			"eventhub_private_endpoint":          {abbreviation: "evhns-pep", rule: rulePrivateEndpoint},
+			"container_app_private_endpoint":     {abbreviation: "cae-pep", rule: rulePrivateEndpoint},
+			"key_vault_private_endpoint":         {abbreviation: "kv-pep", rule: rulePrivateEndpoint},
+			"servicebus_private_endpoint":        {abbreviation: "sbns-pep", rule: rulePrivateEndpoint},
+			"apim_private_endpoint":              {abbreviation: "apim-pep", rule: rulePrivateEndpoint},
+			"app_configuration_private_endpoint": {abbreviation: "appcs-pep", rule: rulePrivateEndpoint},
+			"managed_redis_private_endpoint":     {abbreviation: "amr-pep", rule: rulePrivateEndpoint},
+		},
+
+		"Public IPs": {
+			"public_ip": {abbreviation: "pip", rule: ruleNetwork},
+		},
+
+		"Subnets": {
+			"subnet":                    {abbreviation: "snet", rule: ruleNetwork},
+			"app_subnet":                {abbreviation: "app-snet", rule: ruleNetwork},
+			"apim_subnet":               {abbreviation: "apim-snet", rule: ruleNetwork},
+			"function_subnet":           {abbreviation: "func-snet", rule: ruleNetwork},
+			"container_app_subnet":      {abbreviation: "cae-snet", rule: ruleNetwork},
+			"container_instance_subnet": {abbreviation: "ci-snet", rule: ruleNetwork},
+			"private_endpoint_subnet":   {abbreviation: "pep-snet", rule: ruleNetwork},
+		},
+
+		"Databases": {
+			"cosmos_db_nosql":              {abbreviation: "cosno", rule: ruleCosmosAccount},
+			"customer_key_cosmos_db_nosql": {abbreviation: "cosno-cmk", rule: ruleCosmosAccount},
+			"postgresql":                   {abbreviation: "psql", rule: ruleDatabaseServer},
+			"postgresql_replica":           {abbreviation: "psql-replica", rule: ruleDatabaseServer},
+			"managed_redis":                {abbreviation: "amr", rule: ruleManagedRedis},
+			"redis_cache":                  {abbreviation: "redis", rule: ruleRedisCache},
+			"mysql":                        {abbreviation: "mysql", rule: ruleDatabaseServer},
+		},
+
+		"Integration": {
+			"eventhub_namespace":   {abbreviation: "evhns", rule: ruleMessagingNamespace},
+			"servicebus_namespace": {abbreviation: "sbns", rule: ruleMessagingNamespace},
+			"function_app":         {abbreviation: "func", rule: ruleAppService},
+			"app_service":          {abbreviation: "app", rule: ruleAppService},
+			"app_service_plan":     {abbreviation: "asp", rule: ruleAppServicePlan},
+			"static_web_app":       {abbreviation: "stapp", rule: ruleStaticWebApp},
+			"api_center":           {abbreviation: "apic", rule: ruleAPICenter},
+		},
+
+		"Security": {
+			"key_vault":        {abbreviation: "kv", rule: ruleKeyVault},
+			"managed_identity": {abbreviation: "id", rule: ruleManagedIdentity},
+		},
+
+		"Monitoring": {
+			"application_insights":           {abbreviation: "appi", rule: ruleMonitor},
+			"log_analytics":                  {abbreviation: "log", rule: ruleLogAnalytics},
+			"cdn_monitor_diagnostic_setting": {abbreviation: "cdnp", rule: ruleMonitor},
+			"monitor_alert_sbns_active":      {abbreviation: "sbns-act-ma", rule: ruleMonitor},
+			"monitor_alert_sbns_dlq":         {abbreviation: "sbns-dlq-ma", rule: ruleMonitor},
+		},
+
+		"Miscellaneous": {
+			"resource_group":    {abbreviation: "rg", rule: ruleResourceGroup},
+			"ai_search":         {abbreviation: "srch", rule: ruleAISearch},
+			"load_testing":      {abbreviation: "lt", rule: ruleLoadTesting},
+			"app_configuration": {abbreviation: "appcs", rule: ruleAppConfiguration},
+		},
 	}
 }
 
+// validateResourceAbbreviations checks that every resource type can be told apart from the
+// others once composed into a name: abbreviations must be well-formed and unique, also after
+// removing hyphens for resource types whose names don't contain them.
+func validateResourceAbbreviations(abbreviations map[string]resourceAbbreviation) error {
+	resourceTypes := make([]string, 0, len(abbreviations))
+	for resourceType := range abbreviations {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	owners := make(map[string]string, len(abbreviations))
+	compactOwners := make(map[string]string)
+	for _, resourceType := range resourceTypes {
+		resourceAbbr := abbreviations[resourceType]
+		if !abbreviationPattern.MatchString(resourceAbbr.abbreviation) {
+			return fmt.Errorf("abbreviation '%s' of resource type '%s' must contain lowercase letters and numbers, optionally separated by hyphens", resourceAbbr.abbreviation, resourceType)
+		}
+
+		if owner, exists := owners[resourceAbbr.abbreviation]; exists {
+			return fmt.Errorf("abbreviation '%s' of resource type '%s' is already used by resource type '%s'", resourceAbbr.abbreviation, resourceType, owner)
+		}
+		owners[resourceAbbr.abbreviation] = resourceType
+
+		if resourceAbbr.rule.removeHyphens {
+			compact := strings.ReplaceAll(resourceAbbr.abbreviation, "-", "")
+			if owner, exists := compactOwners[compact]; exists {
+				return fmt.Errorf("abbreviation '%s' of resource type '%s' can't be told apart from the one of resource type '%s' once hyphens are removed", resourceAbbr.abbreviation, resourceType, owner)
+			}
+			compactOwners[compact] = resourceType
+		}
+	}
+
+	return nil
+}
+
 // configurationValues holds the extracted configuration values
 type configurationValues struct {
 	prefix            string
@@ -166,6 +229,7 @@ type configurationValues struct {
 	instanceNumberStr string
 	name              string
 	domain            string
+	truncateStr       string
 }
 
 // extractConfigurationValues extracts and normalizes values from the configuration map
@@ -196,6 +260,11 @@ func extractConfigurationValues(configuration map[string]types.String) configura
 		config.domain = strings.ToLower(domainVal.ValueString())
 	}
 
+	// Extract optional truncate flag
+	if truncateVal, exists := configuration["truncate"]; exists {
+		config.truncateStr = truncateVal.ValueString()
+	}
+
 	return config
 }
 
@@ -218,19 +287,11 @@ func validateEnvironment(environment string) *function.FuncError {
 
 // validateAndNormalizeLocation checks and normalizes the location
 func validateAndNormalizeLocation(location string) (string, *function.FuncError) {
-	locationMappings := map[string]string{
-		"weu":        "weu",
-		"westeurope": "weu",
-		"itn":        "itn",
-		"italynorth": "itn",
-	}
-
-	normalizedLocation := strings.ToLower(location)
-	if normalized, valid := locationMappings[normalizedLocation]; valid {
+	if normalized, valid := normalizeLocation(location); valid {
 		return normalized, nil
 	}
 
-	return "", function.NewFuncError("InvalidLocation: Location must be one of: westeurope, italynorth, weu, itn")
+	return "", function.NewFuncError(fmt.Sprintf("InvalidLocation: Location must be one of: %s, %s", validLongLocations(), validShortLocations()))
 }
 
 // parseInstanceNumber parses and validates the instance number string
@@ -247,20 +308,21 @@ func parseInstanceNumber(instanceNumber string) (int, *function.FuncError) {
 	return instance, nil
 }
 
-// validateResourceType checks if the resource type is valid and returns its abbreviation
-func validateResourceType(resourceType string, abbreviations map[string]string) (string, *function.FuncError) {
+// validateResourceType checks if the resource type is valid and returns its abbreviation and naming rule
+func validateResourceType(resourceType string, abbreviations map[string]resourceAbbreviation) (resourceAbbreviation, *function.FuncError) {
 	abbr, ok := abbreviations[resourceType]
 	if !ok {
-		return "", function.NewFuncError(fmt.Sprintf("InvalidResourceType: resource '%s' not found", resourceType))
+		return resourceAbbreviation{}, function.NewFuncError(fmt.Sprintf("InvalidResourceType: resource '%s' not found", resourceType))
 	}
 	return abbr, nil
 }
 
-// validateRedundancy checks for redundant values between domain, name, and abbreviation
-func validateRedundancy(domain, name, abbreviation string) *function.FuncError {
+// validateRedundancy checks for redundant values between domain, name, and abbreviation,
+// and rejects combinations whose resulting name would parse as a different resource type
+func validateRedundancy(domain, name, resourceType string, abbreviations map[string]resourceAbbreviation) *function.FuncError {
 	normalizedDomain := strings.ToLower(domain)
 	normalizedName := strings.ToLower(name)
-	normalizedAbbreviation := strings.ToLower(abbreviation)
+	normalizedAbbreviation := strings.ToLower(abbreviations[resourceType].abbreviation)
 
 	// Domain and name cannot be the same
 	if domain != "" && normalizedName != "" && normalizedDomain == normalizedName {
@@ -281,9 +343,69 @@ func validateRedundancy(domain, name, abbreviation string) *function.FuncError {
 		return function.NewFuncError("Resource name cannot be part of the resource abbreviation. The abbreviation already contains the name prefix")
 	}
 
+	// Trailing domain and name segments must not form, together with the abbreviation,
+	// the abbreviation of another resource type (e.g. name="blob" with "pep" reads as "blob-pep")
+	if candidate, other, found := findAmbiguousAbbreviation(normalizedDomain, normalizedName, resourceType, abbreviations); found {
+		return function.NewFuncError(fmt.Sprintf("Ambiguous resource name. Domain and name followed by the abbreviation '%s' read as '%s', the abbreviation of resource type '%s'", normalizedAbbreviation, candidate, other))
+	}
+
 	return nil
 }
 
+// findAmbiguousAbbreviation looks for trailing domain and name segments that, followed by the
+// abbreviation of resourceType, match the abbreviation of another resource type. It mirrors
+// parseResourceName, which picks the longest matching abbreviation.
+func findAmbiguousAbbreviation(domain, name, resourceType string, abbreviations map[string]resourceAbbreviation) (string, string, bool) {
+	resourceAbbr := abbreviations[resourceType]
+	compact := resourceAbbr.rule.removeHyphens
+
+	var segments []string
+	for _, value := range []string{domain, name} {
+		if value == "" {
+			continue
+		}
+		if compact {
+			// Names without hyphens can be split at any character
+			value = strings.ReplaceAll(value, "-", "")
+			for i := range value {
+				segments = append(segments, value[i:i+1])
+			}
+			continue
+		}
+		segments = append(segments, strings.Split(value, "-")...)
+	}
+
+	separator := "-"
+	abbreviation := resourceAbbr.abbreviation
+	if compact {
+		separator = ""
+		abbreviation = strings.ReplaceAll(abbreviation, "-", "")
+	}
+
+	for start := range segments {
+		candidate := strings.Join(append(segments[start:len(segments):len(segments)], abbreviation), separator)
+		if other, ok := findResourceTypeByAbbreviation(candidate, abbreviations, compact); ok && other != resourceType {
+			return candidate, other, true
+		}
+	}
+
+	return "", "", false
+}
+
+// parseTruncate parses the optional truncate flag, which defaults to false
+func parseTruncate(truncate string) (bool, *function.FuncError) {
+	if truncate == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(truncate)
+	if err != nil {
+		return false, function.NewFuncError("The truncate value must be a valid boolean")
+	}
+
+	return enabled, nil
+}
+
 // buildResourceName constructs the final resource name
 func buildResourceName(prefix, environment, location, domain, name, abbreviation string, instance int) string {
 	// Start with base: prefix-environment-location
@@ -306,24 +428,114 @@ func buildResourceName(prefix, environment, location, domain, name, abbreviation
 	return strings.ToLower(fmt.Sprintf("%s-%02d", result, instance))
 }
 
+// composeResourceName builds the resource name and applies the hyphen policy of the resource type
+func composeResourceName(prefix, environment, location, domain, name string, resourceAbbr resourceAbbreviation, instance int) string {
+	result := buildResourceName(prefix, environment, location, domain, name, resourceAbbr.abbreviation, instance)
+
+	// Special handling for resources that don't accept hyphens (e.g. storage accounts)
+	if resourceAbbr.rule.removeHyphens {
+		result = strings.ReplaceAll(result, "-", "")
+	}
+
+	return result
+}
+
+// shortenSegments deterministically shortens the domain and name segments so that
+// the resource name fits the maximum length of its resource type. Vowels are dropped
+// first; if that's not enough, the segments are merged, cut and suffixed with a short
+// hash of the original values to keep distinct inputs distinct. Separators left at the
+// edges of a shortened segment are removed, so it never yields empty or repeated hyphens.
+// The resulting name may still be too long when the fixed parts alone exceed the limit.
+func shortenSegments(prefix, environment, location, domain, name string, resourceAbbr resourceAbbreviation, instance int) (string, string) {
+	maxLength := resourceAbbr.rule.maxLength
+	if domain == "" && name == "" {
+		return domain, name
+	}
+
+	shortDomain, shortName := trimSeparators(dropVowels(domain)), trimSeparators(dropVowels(name))
+	result := composeResourceName(prefix, environment, location, shortDomain, shortName, resourceAbbr, instance)
+	if len(result) <= maxLength {
+		return shortDomain, shortName
+	}
+
+	hash := sha256.Sum256([]byte(domain + "/" + name))
+	suffix := hex.EncodeToString(hash[:])[:truncateHashLength]
+
+	// Space left for the merged segment once prefix, environment, location, abbreviation and instance are in place
+	fixedLength := len(composeResourceName(prefix, environment, location, "", "x", resourceAbbr, instance)) - 1
+	available := maxLength - fixedLength
+	segment := strings.ReplaceAll(shortDomain+shortName, "-", "")
+	if keep := available - truncateHashLength; keep < len(segment) {
+		segment = segment[:max(keep, 0)]
+	}
+
+	return "", segment + suffix
+}
+
+// truncateHashLength is the number of hex characters of the hash appended by shortenSegments
+const truncateHashLength = 4
+
+// dropVowels removes the vowels of each hyphen separated word, keeping its first character
+func dropVowels(value string) string {
+	words := strings.Split(value, "-")
+	for i, word := range words {
+		if word == "" {
+			continue
+		}
+		var b strings.Builder
+		b.WriteByte(word[0])
+		for _, c := range word[1:] {
+			if !strings.ContainsRune("aeiou", c) {
+				b.WriteRune(c)
+			}
+		}
+		words[i] = b.String()
+	}
+	return strings.Join(words, "-")
+}
+
+// trimSeparators removes the hyphens at the edges of a segment and collapses repeated ones
+func trimSeparators(value string) string {
+	words := strings.FieldsFunc(value, func(r rune) bool { return r == '-' })
+	return strings.Join(words, "-")
+}
+
 func (f *resourceNameFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
 	var configuration map[string]types.String
+	var customResourceTypes []types.Dynamic
 
-	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &configuration))
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &configuration, &customResourceTypes))
 	if resp.Error != nil {
 		return
 	}
 
+	abbreviations, err := customResourceTypesFromArguments(customResourceTypes)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	result, err := generateResourceName(configuration, abbreviations)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, result))
+}
+
+// generateResourceName validates the configuration map and returns the resource name.
+// It is shared by every function generating names, so they all apply the same rules.
+func generateResourceName(configuration map[string]types.String, abbreviations map[string]resourceAbbreviation) (string, *function.FuncError) {
 	// Define and validate configuration keys
 	requiredKeys := []string{"prefix", "location", "resource_type", "instance_number"}
-	optionalKeys := []string{"domain", "name", "app_name", "environment", "env_short"}
+	optionalKeys := []string{"domain", "name", "app_name", "environment", "env_short", "truncate"}
 	allowedKeys := append(requiredKeys, optionalKeys...)
 
 	// Validate required keys are present
 	for _, key := range requiredKeys {
 		if _, exists := configuration[key]; !exists {
-			resp.Error = function.NewFuncError(fmt.Sprintf("Missing key in input. The required key '%s' is missing from the input map", key))
-			return
+			return "", function.NewFuncError(fmt.Sprintf("Missing key in input. The required key '%s' is missing from the input map", key))
 		}
 	}
 
@@ -331,27 +543,23 @@ func (f *resourceNameFunction) Run(ctx context.Context, req function.RunRequest,
 	_, hasEnvironment := configuration["environment"]
 	_, hasEnvShort := configuration["env_short"]
 	if !hasEnvironment && !hasEnvShort {
-		resp.Error = function.NewFuncError("Missing required configuration key: either 'environment' or 'env_short' must be provided")
-		return
+		return "", function.NewFuncError("Missing required configuration key: either 'environment' or 'env_short' must be provided")
 	}
 	if hasEnvironment && hasEnvShort {
-		resp.Error = function.NewFuncError("Invalid key combination. 'environment' and 'env_short' are mutually exclusive, provide only one.")
-		return
+		return "", function.NewFuncError("Invalid key combination. 'environment' and 'env_short' are mutually exclusive, provide only one.")
 	}
 
 	// Validate that 'name' and 'app_name' are not both provided
 	_, hasName := configuration["name"]
 	_, hasAppName := configuration["app_name"]
 	if hasName && hasAppName {
-		resp.Error = function.NewFuncError("Invalid key combination. 'name' and 'app_name' are mutually exclusive, provide only one")
-		return
+		return "", function.NewFuncError("Invalid key combination. 'name' and 'app_name' are mutually exclusive, provide only one")
 	}
 
 	// Validate no unexpected keys are provided
 	for key := range configuration {
 		if !contains(allowedKeys, key) {
-			resp.Error = function.NewFuncError(fmt.Sprintf("Invalid key in input. The key '%s' is not allowed", key))
-			return
+			return "", function.NewFuncError(fmt.Sprintf("Invalid key in input. The key '%s' is not allowed", key))
 		}
 	}
 
@@ -360,49 +568,58 @@ func (f *resourceNameFunction) Run(ctx context.Context, req function.RunRequest,
 
 	// Validate all inputs
 	if err := validatePrefix(config.prefix); err != nil {
-		resp.Error = err
-		return
+		return "", err
 	}
 
 	if err := validateEnvironment(config.environment); err != nil {
-		resp.Error = err
-		return
+		return "", err
 	}
 
 	normalizedLocation, err := validateAndNormalizeLocation(config.location)
 	if err != nil {
-		resp.Error = err
-		return
+		return "", err
 	}
 
 	instance, err := parseInstanceNumber(config.instanceNumberStr)
 	if err != nil {
-		resp.Error = err
-		return
+		return "", err
 	}
 
-	abbreviations := getResourceAbbreviations()
-	abbreviation, err := validateResourceType(config.resourceType, abbreviations)
+	truncate, err := parseTruncate(config.truncateStr)
 	if err != nil {
-		resp.Error = err
-		return
+		return "", err
+	}
+
+	resourceAbbr, err := validateResourceType(config.resourceType, abbreviations)
+	if err != nil {
+		return "", err
 	}
 
 	// Validate no redundancy between domain, name, and abbreviation
-	if err := validateRedundancy(config.domain, config.name, abbreviation); err != nil {
-		resp.Error = err
-		return
+	if err := validateRedundancy(config.domain, config.name, config.resourceType, abbreviations); err != nil {
+		return "", err
 	}
 
 	// Build the final resource name
-	result := buildResourceName(config.prefix, config.environment, normalizedLocation, config.domain, config.name, abbreviation, instance)
+	result := composeResourceName(config.prefix, config.environment, normalizedLocation, config.domain, config.name, resourceAbbr, instance)
 
-	// Special handling for storage accounts (remove hyphens)
-	if strings.Contains(config.resourceType, "storage_account") {
-		result = strings.ReplaceAll(result, "-", "")
+	// Shorten domain and name when explicitly requested and the name is too long
+	if truncate && len(result) > resourceAbbr.rule.maxLength {
+		domain, name := shortenSegments(config.prefix, config.environment, normalizedLocation, config.domain, config.name, resourceAbbr, instance)
+		result = composeResourceName(config.prefix, config.environment, normalizedLocation, domain, name, resourceAbbr, instance)
+
+		// Shortening can make the segments redundant or ambiguous even when the original ones were not
+		if err := validateRedundancy(domain, name, config.resourceType, abbreviations); err != nil {
+			return "", function.NewFuncError(fmt.Sprintf("Invalid shortened resource name '%s'. %s", result, err.Text))
+		}
 	}
 
-	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, result))
+	// Fail at plan time if Azure would reject the name at apply time
+	if err := validateNamingRule(config.resourceType, result, resourceAbbr.rule); err != nil {
+		return "", err
+	}
+
+	return result, nil
 }
 
 func contains(list []string, target string) bool {
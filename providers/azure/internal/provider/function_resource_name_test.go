@@ -95,7 +95,7 @@ func TestResourceNameFunction_InvalidLocation(t *testing.T) {
 					})
         }
         `,
-				ExpectError: regexp.MustCompile(`Location must be one of: westeurope, italynorth, weu, itn`),
+				ExpectError: regexp.MustCompile(`InvalidLocation:[\s\n]+Location must be one of`),
 			},
 		},
 	})
@@ -324,6 +324,12 @@ func TestResourceNameFunction_AllLocations(t *testing.T) {
 		{"westeurope", "dx-d-weu-test-vm-01"},
 		{"itn", "dx-d-itn-test-vm-01"},
 		{"italynorth", "dx-d-itn-test-vm-01"},
+		{"swc", "dx-d-swc-test-vm-01"},
+		{"swedencentral", "dx-d-swc-test-vm-01"},
+		{"spc", "dx-d-spc-test-vm-01"},
+		{"SpainCentral", "dx-d-spc-test-vm-01"},
+		{"neu", "dx-d-neu-test-vm-01"},
+		{"germanycentral", "dx-d-gwc-test-vm-01"},
 	}
 
 	for _, tc := range testCases {
@@ -1026,3 +1032,396 @@ func TestResourceNameFunction_ManagedRedisPrivateEndpoint(t *testing.T) {
 		},
 	})
 }
+
+func TestResourceNameFunction_MergedConfiguration(t *testing.T) {
+	t.Parallel()
+	// Provider functions do not see the provider block, so shared values are
+	// passed through a merged local map. Keys in the second map win.
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig + `
+        locals {
+          naming_config = {
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						domain = "test",
+					}
+        }
+
+        output "test" {
+          value = provider::dx::resource_name(merge(local.naming_config, {
+						name = "example",
+						resource_type = "app_service",
+						instance_number = 1
+					}))
+        }
+
+        output "override" {
+          value = provider::dx::resource_name(merge(local.naming_config, {
+						location = "weu",
+						name = "example",
+						resource_type = "app_service",
+						instance_number = 2
+					}))
+        }
+        `,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact("dx-d-itn-test-example-app-01")),
+					statecheck.ExpectKnownOutputValue("override", knownvalue.StringExact("dx-d-weu-test-example-app-02")),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_NamingRuleMaxLength(t *testing.T) {
+	t.Parallel()
+	// Test that names exceeding the Azure limit of the resource type fail at plan time
+	testCases := []struct {
+		resourceType string
+		expected     string
+	}{
+		{"key_vault", `at[\s\n]+most[\s\n]+24[\s\n]+characters`},
+		{"storage_account", `at[\s\n]+most[\s\n]+24[\s\n]+characters`},
+	}
+
+	for _, tc := range testCases {
+		t.Run("resource_type_"+tc.resourceType, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: `
+            output "test" {
+              value = provider::dx::resource_name({
+								prefix = "dx",
+								environment = "d",
+								location = "itn",
+								domain = "selfcare",
+								name = "onboarding",
+								resource_type = "` + tc.resourceType + `",
+								instance_number = "1"
+							})
+            }
+            `,
+						ExpectError: regexp.MustCompile(tc.expected),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestResourceNameFunction_NamingRuleCharset(t *testing.T) {
+	t.Parallel()
+	// Test that characters Azure does not accept for the resource type are rejected
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						name = "my_app",
+						resource_type = "app_service",
+						instance_number = "1"
+					})
+        }
+        `,
+				ExpectError: regexp.MustCompile(`contains[\s\n]+characters[\s\n]+not[\s\n]+allowed`),
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_NamingRuleStartWithLetter(t *testing.T) {
+	t.Parallel()
+	// Test that resource types requiring a leading letter reject numeric prefixes
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "01",
+						environment = "d",
+						location = "itn",
+						name = "app",
+						resource_type = "key_vault",
+						instance_number = "1"
+					})
+        }
+        `,
+				ExpectError: regexp.MustCompile(`must[\s\n]+start[\s\n]+with[\s\n]+a[\s\n]+letter`),
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_TableStorageRemovesHyphens(t *testing.T) {
+	t.Parallel()
+	// Azure table names only accept alphanumerics, so hyphens are removed
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						name = "events",
+						resource_type = "table_storage",
+						instance_number = "1"
+					})
+        }
+        `,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact("dxditneventstable01")),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_Truncate(t *testing.T) {
+	t.Parallel()
+	// Test that truncate shortens domain and name to fit the resource type limit
+	testCases := []struct {
+		resourceType string
+		expected     string
+	}{
+		// Dropping vowels is enough
+		{"storage_account", "dxditnslfcronbrdngst01"},
+		// Dropping vowels is not enough, segments are cut and hashed
+		{"key_vault", "dx-d-itn-slfcr1346-kv-01"},
+		// Names already within the limit are left untouched
+		{"app_service", "dx-d-itn-selfcare-onboarding-app-01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run("resource_type_"+tc.resourceType, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: `
+            output "test" {
+              value = provider::dx::resource_name({
+								prefix = "dx",
+								environment = "d",
+								location = "itn",
+								domain = "selfcare",
+								name = "onboarding",
+								resource_type = "` + tc.resourceType + `",
+								instance_number = "1",
+								truncate = true
+							})
+            }
+            `,
+						ConfigStateChecks: []statecheck.StateCheck{
+							statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact(tc.expected)),
+						},
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestResourceNameFunction_TruncateTrailingSeparator(t *testing.T) {
+	t.Parallel()
+	// Test that shortening does not leave a separator at the end of a segment
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						domain = "selfcare",
+						name = "onboarding-",
+						resource_type = "container_app",
+						instance_number = "1",
+						truncate = true
+					})
+        }
+        `,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.StringExact("dx-d-itn-slfcr-onbrdng-ca-01")),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_TruncateRedundancy(t *testing.T) {
+	t.Parallel()
+	// Test that the shortened segments are validated again: dropping the vowels of the name makes it equal to the domain
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						domain = "slfcrxyz",
+						name = "selfcarexyz",
+						resource_type = "container_app",
+						instance_number = "1",
+						truncate = true
+					})
+        }
+        `,
+				ExpectError: regexp.MustCompile(`Invalid shortened[\s\n]+resource name[\s\n]+'dx-d-itn-slfcrxyz-slfcrxyz-ca-01'\.[\s\n]+Resource domain[\s\n]+cannot[\s\n]+be[\s\n]+the same as the resource name`),
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_InvalidTruncate(t *testing.T) {
+	t.Parallel()
+	// Test that a non boolean truncate value is rejected
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+        output "test" {
+          value = provider::dx::resource_name({
+						prefix = "dx",
+						environment = "d",
+						location = "itn",
+						name = "app",
+						resource_type = "key_vault",
+						instance_number = "1",
+						truncate = "maybe"
+					})
+        }
+        `,
+				ExpectError: regexp.MustCompile(`The truncate value[\s\n]+must be a valid boolean`),
+			},
+		},
+	})
+}
+
+func TestResourceNameFunction_AmbiguousName(t *testing.T) {
+	t.Parallel()
+
+	// Each name would parse back as a different resource type
+	cases := map[string]string{
+		"name completes the abbreviation":        `name = "blob", resource_type = "private_endpoint"`,
+		"last name segment completes it":         `name = "my-app", resource_type = "subnet"`,
+		"domain completes it when name is empty": `domain = "kv", resource_type = "private_endpoint"`,
+	}
+
+	for name, keys := range cases {
+		keys := keys
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			resource.UnitTest(t, resource.TestCase{
+				TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+					tfversion.SkipBelow(tfversion.Version1_8_0),
+				},
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: `
+output "test" {
+  value = provider::dx::resource_name({
+    prefix = "dx", environment = "d", location = "itn", instance_number = 1,
+    ` + keys + `
+  })
+}
+`,
+						ExpectError: regexp.MustCompile(`Ambiguous[\s\n]+resource[\s\n]+name`),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestResourceNameFunction_AmbiguousCompactName(t *testing.T) {
+	t.Parallel()
+	// "myst" followed by the custom "fn" abbreviation reads as "stfn" (function_storage_account)
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_name({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    name            = "myst",
+    resource_type   = "function_share",
+    instance_number = 1,
+  }, { function_share = { abbreviation = "fn", remove_hyphens = true } })
+}
+`,
+				ExpectError: regexp.MustCompile(`the[\s\n]+abbreviation[\s\n]+of[\s\n]+resource[\s\n]+type[\s\n]+'function_storage_account'`),
+			},
+		},
+	})
+}
+
+func TestResourceAbbreviations(t *testing.T) {
+	t.Parallel()
+
+	if err := validateResourceAbbreviations(getResourceAbbreviations()); err != nil {
+		t.Fatalf("invalid built-in resource abbreviations: %s", err)
+	}
+
+	// Compact resource types can't share an abbreviation even if the hyphenated forms differ
+	abbreviations := getResourceAbbreviations()
+	abbreviations["function_storage_account_copy"] = resourceAbbreviation{abbreviation: "st-fn", rule: ruleStorageAccount}
+	if err := validateResourceAbbreviations(abbreviations); err == nil {
+		t.Fatal("expected an error for abbreviations colliding once hyphens are removed")
+	}
+}
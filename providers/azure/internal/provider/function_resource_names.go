@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &resourceNamesFunction{}
+
+type resourceNamesFunction struct{}
+
+func NewResourceNamesFunction() function.Function {
+	return &resourceNamesFunction{}
+}
+
+func (f *resourceNamesFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "resource_names"
+}
+
+func (f *resourceNamesFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:     "Return Azure dx resources naming convention for many resource types at once",
+		Description: "Given a shared configuration and a list of resource types (or a map of resource type to instance number), returns a map of resource type to name following the Azure dx resources naming convention. Each name is validated exactly like resource_name.",
+
+		Parameters: []function.Parameter{
+			function.MapParameter{
+				Name:           "configuration",
+				Description:    "A map containing the keys accepted by resource_name except resource_type: prefix, environment (or env_short), location, domain (Optional), name (or app_name - Optional), instance_number (Optional when resource_types is a map) and truncate (Optional).",
+				ElementType:    types.StringType,
+				AllowNullValue: true,
+			},
+			function.DynamicParameter{
+				Name:        "resource_types",
+				Description: "Either a list of resource types, named with the instance_number of the configuration, or a map of resource type to instance number.",
+			},
+		},
+		VariadicParameter: customResourceTypesParameter(),
+		Return: function.MapReturn{
+			ElementType: types.StringType,
+		},
+	}
+}
+
+func (f *resourceNamesFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var configuration map[string]types.String
+	var resourceTypes types.Dynamic
+	var customResourceTypes []types.Dynamic
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, req.Arguments.Get(ctx, &configuration, &resourceTypes, &customResourceTypes))
+	if resp.Error != nil {
+		return
+	}
+
+	if _, exists := configuration["resource_type"]; exists {
+		resp.Error = function.NewFuncError("Invalid key in input. The key 'resource_type' is not allowed, list the resource types in the resource_types argument")
+		return
+	}
+
+	abbreviations, err := customResourceTypesFromArguments(customResourceTypes)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	instances, err := extractResourceTypeInstances(resourceTypes)
+	if err != nil {
+		resp.Error = err
+		return
+	}
+
+	// Sort the resource types so errors are reported deterministically
+	keys := make([]string, 0, len(instances))
+	for resourceType := range instances {
+		keys = append(keys, resourceType)
+	}
+	sort.Strings(keys)
+
+	result := make(map[string]string, len(instances))
+	for _, resourceType := range keys {
+		resourceConfiguration := maps.Clone(configuration)
+		if resourceConfiguration == nil {
+			resourceConfiguration = map[string]types.String{}
+		}
+		resourceConfiguration["resource_type"] = types.StringValue(resourceType)
+		if instance := instances[resourceType]; instance != "" {
+			resourceConfiguration["instance_number"] = types.StringValue(instance)
+		}
+
+		name, err := generateResourceName(resourceConfiguration, abbreviations)
+		if err != nil {
+			resp.Error = function.NewFuncError(fmt.Sprintf("Resource type '%s': %s", resourceType, err.Text))
+			return
+		}
+		result[resourceType] = name
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Error, resp.Result.Set(ctx, result))
+}
+
+// extractResourceTypeInstances converts the resource_types argument into a map of
+// resource type to instance number. Resource types given as a list map to an empty
+// instance number, meaning the one in the configuration is used.
+func extractResourceTypeInstances(resourceTypes types.Dynamic) (map[string]string, *function.FuncError) {
+	if resourceTypes.IsNull() || resourceTypes.IsUnderlyingValueNull() {
+		return nil, function.NewFuncError("The resource_types argument must not be null")
+	}
+
+	var (
+		list    []attr.Value
+		mapping map[string]attr.Value
+	)
+	switch value := resourceTypes.UnderlyingValue().(type) {
+	case types.List:
+		list = value.Elements()
+	case types.Set:
+		list = value.Elements()
+	case types.Tuple:
+		list = value.Elements()
+	case types.Map:
+		mapping = value.Elements()
+	case types.Object:
+		mapping = value.Attributes()
+	default:
+		return nil, function.NewFuncError("The resource_types argument must be a list of resource types or a map of resource type to instance number")
+	}
+
+	instances := make(map[string]string, len(list)+len(mapping))
+	for _, element := range list {
+		resourceType, ok := primitiveToString(element)
+		if !ok {
+			return nil, function.NewFuncError("The resource_types list must only contain strings")
+		}
+		if _, duplicate := instances[resourceType]; duplicate {
+			return nil, function.NewFuncError(fmt.Sprintf("The resource type '%s' is listed more than once", resourceType))
+		}
+		instances[resourceType] = ""
+	}
+	for resourceType, element := range mapping {
+		instance, ok := primitiveToString(element)
+		if !ok || instance == "" {
+			return nil, function.NewFuncError(fmt.Sprintf("The instance number of resource type '%s' must be a number or a string", resourceType))
+		}
+		instances[resourceType] = instance
+	}
+
+	if len(instances) == 0 {
+		return nil, function.NewFuncError("The resource_types argument must contain at least one resource type")
+	}
+
+	return instances, nil
+}
+
+// primitiveToString returns the string representation of a known string, number or bool value
+func primitiveToString(value attr.Value) (string, bool) {
+	if value.IsNull() || value.IsUnknown() {
+		return "", false
+	}
+
+	switch v := value.(type) {
+	case types.String:
+		return v.ValueString(), true
+	case types.Number:
+		return v.ValueBigFloat().Text('f', -1), true
+	case types.Int64:
+		return fmt.Sprintf("%d", v.ValueInt64()), true
+	case types.Bool:
+		return fmt.Sprintf("%t", v.ValueBool()), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestResourceNamesFunction_List(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_names({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    domain          = "test",
+    name            = "web",
+    instance_number = 1,
+  }, ["app_service", "storage_account", "key_vault"])
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.MapExact(map[string]knownvalue.Check{
+						"app_service":     knownvalue.StringExact("dx-d-itn-test-web-app-01"),
+						"storage_account": knownvalue.StringExact("dxditntestwebst01"),
+						"key_vault":       knownvalue.StringExact("dx-d-itn-test-web-kv-01"),
+					})),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNamesFunction_Map(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_names({
+    prefix      = "dx",
+    environment = "d",
+    location    = "itn",
+    name        = "web",
+  }, {
+    app_service      = 2,
+    app_service_plan = "1",
+  })
+}
+`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.MapExact(map[string]knownvalue.Check{
+						"app_service":      knownvalue.StringExact("dx-d-itn-web-app-02"),
+						"app_service_plan": knownvalue.StringExact("dx-d-itn-web-asp-01"),
+					})),
+				},
+			},
+		},
+	})
+}
+
+func TestResourceNamesFunction_InvalidResourceType(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_names({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    name            = "web",
+    instance_number = 1,
+  }, ["app_service", "invalid_type"])
+}
+`,
+				ExpectError: regexp.MustCompile(`Resource type[\s\n]+'invalid_type':[\s\n]+InvalidResourceType:[\s\n]+resource[\s\n]+'invalid_type'[\s\n]+not[\s\n]+found`),
+			},
+		},
+	})
+}
+
+func TestResourceNamesFunction_ResourceTypeKeyNotAllowed(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_names({
+    prefix          = "dx",
+    environment     = "d",
+    location        = "itn",
+    resource_type   = "app_service",
+    instance_number = 1,
+  }, ["key_vault"])
+}
+`,
+				ExpectError: regexp.MustCompile(`The key 'resource_type'[\s\n]+is[\s\n]+not[\s\n]+allowed`),
+			},
+		},
+	})
+}
+
+func TestResourceNamesFunction_MissingInstanceNumber(t *testing.T) {
+	t.Parallel()
+	resource.UnitTest(t, resource.TestCase{
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+output "test" {
+  value = provider::dx::resource_names({
+    prefix      = "dx",
+    environment = "d",
+    location    = "itn",
+  }, ["key_vault"])
+}
+`,
+				ExpectError: regexp.MustCompile(`Missing key in input`),
+			},
+		},
+	})
+}
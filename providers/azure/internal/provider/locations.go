@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"sort"
+	"strings"
+)
+
+// locationShortToLong is the single source of truth mapping short location codes
+// to their full Azure region names. locationLongToShort is derived from this map.
+// It backs resource_name, the convert_location_* functions and the provider
+// location validator, so adding a region here enables it everywhere.
+var locationShortToLong = map[string]string{
+	"gwc": "germanycentral",
+	"itn": "italynorth",
+	"neu": "northeurope",
+	"spc": "spaincentral",
+	"swc": "swedencentral",
+	"weu": "westeurope",
+}
+
+// locationLongToShort is derived from locationShortToLong at init time.
+var locationLongToShort map[string]string
+
+func init() {
+	locationLongToShort = make(map[string]string, len(locationShortToLong))
+	for short, long := range locationShortToLong {
+		locationLongToShort[long] = short
+	}
+}
+
+// validShortLocations returns a stable, sorted comma-separated list of valid short location codes.
+func validShortLocations() string {
+	keys := make([]string, 0, len(locationShortToLong))
+	for k := range locationShortToLong {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// validLongLocations returns a stable, sorted comma-separated list of valid long location names.
+func validLongLocations() string {
+	keys := make([]string, 0, len(locationLongToShort))
+	for k := range locationLongToShort {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+// supportedLocations returns every accepted location value, both short codes and long names.
+func supportedLocations() []string {
+	locations := make([]string, 0, len(locationShortToLong)*2)
+	for short, long := range locationShortToLong {
+		locations = append(locations, short, long)
+	}
+	sort.Strings(locations)
+	return locations
+}
+
+// normalizeLocation returns the short code for a short or long location, ignoring case.
+func normalizeLocation(location string) (string, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(location))
+	if _, ok := locationShortToLong[normalized]; ok {
+		return normalized, true
+	}
+	short, ok := locationLongToShort[normalized]
+	return short, ok
+}
@@ -0,0 +1,120 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// resourceAbbreviation describes a supported resource type: the abbreviation used
+// in generated names, the Azure naming rule the final name must satisfy and the
+// category it is listed under.
+type resourceAbbreviation struct {
+	abbreviation string
+	rule         namingRule
+	category     string
+}
+
+// namingCharset is the set of characters Azure accepts in a resource name.
+// Generated names are always lowercase, so patterns only list lowercase letters.
+type namingCharset struct {
+	pattern     *regexp.Regexp
+	description string
+}
+
+var (
+	charsetAlphanumeric = namingCharset{
+		pattern:     regexp.MustCompile(`^[a-z0-9]+$`),
+		description: "lowercase letters and numbers",
+	}
+	charsetAlphanumericHyphens = namingCharset{
+		pattern:     regexp.MustCompile(`^[a-z0-9-]+$`),
+		description: "lowercase letters, numbers and hyphens",
+	}
+	charsetAlphanumericHyphensUnderscores = namingCharset{
+		pattern:     regexp.MustCompile(`^[a-z0-9_-]+$`),
+		description: "lowercase letters, numbers, hyphens and underscores",
+	}
+	charsetAlphanumericHyphensUnderscoresPeriods = namingCharset{
+		pattern:     regexp.MustCompile(`^[a-z0-9_.-]+$`),
+		description: "lowercase letters, numbers, hyphens, underscores and periods",
+	}
+	charsetResourceGroup = namingCharset{
+		pattern:     regexp.MustCompile(`^[a-z0-9_.()-]+$`),
+		description: "lowercase letters, numbers, hyphens, underscores, periods and parentheses",
+	}
+)
+
+// namingRule holds the Azure naming constraints of a resource type.
+// See https://learn.microsoft.com/en-us/azure/azure-resource-manager/management/resource-name-rules
+type namingRule struct {
+	minLength           int
+	maxLength           int
+	charset             namingCharset
+	removeHyphens       bool
+	mustStartWithLetter bool
+}
+
+var (
+	ruleVirtualMachine          = namingRule{minLength: 1, maxLength: 64, charset: charsetAlphanumericHyphensUnderscoresPeriods}
+	ruleContainerApp            = namingRule{minLength: 2, maxLength: 32, charset: charsetAlphanumericHyphens, mustStartWithLetter: true}
+	ruleContainerAppEnvironment = namingRule{minLength: 2, maxLength: 60, charset: charsetAlphanumericHyphens, mustStartWithLetter: true}
+	ruleContainerInstance       = namingRule{minLength: 1, maxLength: 63, charset: charsetAlphanumericHyphens}
+	ruleStorageAccount          = namingRule{minLength: 3, maxLength: 24, charset: charsetAlphanumeric, removeHyphens: true}
+	ruleStorageChild            = namingRule{minLength: 3, maxLength: 63, charset: charsetAlphanumericHyphens}
+	ruleStorageTable            = namingRule{minLength: 3, maxLength: 63, charset: charsetAlphanumeric, removeHyphens: true, mustStartWithLetter: true}
+	ruleAPIManagement           = namingRule{minLength: 1, maxLength: 50, charset: charsetAlphanumericHyphens, mustStartWithLetter: true}
+	ruleVirtualNetwork          = namingRule{minLength: 2, maxLength: 64, charset: charsetAlphanumericHyphensUnderscoresPeriods}
+	ruleNetwork                 = namingRule{minLength: 1, maxLength: 80, charset: charsetAlphanumericHyphensUnderscoresPeriods}
+	rulePrivateEndpoint         = namingRule{minLength: 2, maxLength: 64, charset: charsetAlphanumericHyphensUnderscoresPeriods}
+	ruleFrontDoorProfile        = namingRule{minLength: 1, maxLength: 260, charset: charsetAlphanumericHyphens}
+	ruleFrontDoorEndpoint       = namingRule{minLength: 1, maxLength: 46, charset: charsetAlphanumericHyphens}
+	ruleFrontDoorChild          = namingRule{minLength: 1, maxLength: 90, charset: charsetAlphanumericHyphens}
+	ruleCosmosAccount           = namingRule{minLength: 3, maxLength: 44, charset: charsetAlphanumericHyphens}
+	ruleDatabaseServer          = namingRule{minLength: 3, maxLength: 63, charset: charsetAlphanumericHyphens}
+	ruleManagedRedis            = namingRule{minLength: 1, maxLength: 60, charset: charsetAlphanumericHyphens}
+	ruleRedisCache              = namingRule{minLength: 1, maxLength: 63, charset: charsetAlphanumericHyphens}
+	ruleMessagingNamespace      = namingRule{minLength: 6, maxLength: 50, charset: charsetAlphanumericHyphens, mustStartWithLetter: true}
+	ruleAppService              = namingRule{minLength: 2, maxLength: 60, charset: charsetAlphanumericHyphens}
+	ruleAppServicePlan          = namingRule{minLength: 1, maxLength: 60, charset: charsetAlphanumericHyphens}
+	ruleStaticWebApp            = namingRule{minLength: 2, maxLength: 40, charset: charsetAlphanumericHyphens}
+	ruleAPICenter               = namingRule{minLength: 1, maxLength: 90, charset: charsetAlphanumericHyphens}
+	ruleKeyVault                = namingRule{minLength: 3, maxLength: 24, charset: charsetAlphanumericHyphens, mustStartWithLetter: true}
+	ruleManagedIdentity         = namingRule{minLength: 3, maxLength: 128, charset: charsetAlphanumericHyphensUnderscores}
+	ruleLogAnalytics            = namingRule{minLength: 4, maxLength: 63, charset: charsetAlphanumericHyphens}
+	ruleMonitor                 = namingRule{minLength: 1, maxLength: 260, charset: charsetAlphanumericHyphensUnderscoresPeriods}
+	ruleResourceGroup           = namingRule{minLength: 1, maxLength: 90, charset: charsetResourceGroup}
+	ruleAISearch                = namingRule{minLength: 2, maxLength: 60, charset: charsetAlphanumericHyphens}
+	ruleLoadTesting             = namingRule{minLength: 1, maxLength: 64, charset: charsetAlphanumericHyphensUnderscores}
+	ruleAppConfiguration        = namingRule{minLength: 5, maxLength: 50, charset: charsetAlphanumericHyphens}
+)
+
+// validateNamingRule checks the generated name against the Azure naming rule of its resource type
+func validateNamingRule(resourceType, name string, rule namingRule) *function.FuncError {
+	if len(name) > rule.maxLength {
+		return function.NewFuncError(fmt.Sprintf(
+			"InvalidResourceName: name '%s' is %d characters long, but Azure allows at most %d characters for resource type '%s'",
+			name, len(name), rule.maxLength, resourceType))
+	}
+
+	if len(name) < rule.minLength {
+		return function.NewFuncError(fmt.Sprintf(
+			"InvalidResourceName: name '%s' is %d characters long, but Azure requires at least %d characters for resource type '%s'",
+			name, len(name), rule.minLength, resourceType))
+	}
+
+	if !rule.charset.pattern.MatchString(name) {
+		return function.NewFuncError(fmt.Sprintf(
+			"InvalidResourceName: name '%s' contains characters not allowed for resource type '%s', Azure allows only %s",
+			name, resourceType, rule.charset.description))
+	}
+
+	if rule.mustStartWithLetter && (name[0] < 'a' || name[0] > 'z') {
+		return function.NewFuncError(fmt.Sprintf(
+			"InvalidResourceName: name '%s' must start with a letter for resource type '%s'",
+			name, resourceType))
+	}
+
+	return nil
+}
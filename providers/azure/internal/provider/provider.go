@@ -2,30 +2,74 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
 )
 
-var _ provider.Provider = &dxProvider{}
+var (
+	_ provider.Provider                   = &dxProvider{}
+	_ provider.ProviderWithValidateConfig = &dxProvider{}
+)
 
 type dxProvider struct {
 	Version string
+
+	// network replaces the Azure SDK clients built from the configuration, nil outside of tests
+	network networkClient
 }
 
 type dxPrefix string
 
+// dxProviderData is shared with resources and data sources once the provider is configured
+type dxProviderData struct {
+	// resourceAbbreviations are the built-in resource types extended with the custom ones
+	resourceAbbreviations map[string]resourceAbbreviation
+	// reservationStore persists subnet CIDR reservations, nil when not configured
+	reservationStore cidrReservationStore
+	// network reads the Virtual Networks, built once and shared by every resource and data source
+	network networkClient
+	// cidrPreviews holds the CIDR blocks previewed by the resources planned with this configuration
+	cidrPreviews *cidrPreviews
+}
+
 type dxProviderModel struct {
 	Prefix      types.String `tfsdk:"prefix"`
 	Domain      types.String `tfsdk:"domain"`
 	Environment types.String `tfsdk:"environment"`
 	Location    types.String `tfsdk:"location"`
+
+	CustomResourceTypes   types.Map    `tfsdk:"custom_resource_types"`
+	SubnetCidrReservation types.Object `tfsdk:"subnet_cidr_reservation"`
+
+	UseCLI            types.Bool   `tfsdk:"use_cli"`
+	UseMSI            types.Bool   `tfsdk:"use_msi"`
+	UseOIDC           types.Bool   `tfsdk:"use_oidc"`
+	ClientID          types.String `tfsdk:"client_id"`
+	TenantID          types.String `tfsdk:"tenant_id"`
+	OIDCTokenFilePath types.String `tfsdk:"oidc_token_file_path"`
+
+	AzureEnvironment types.String `tfsdk:"azure_environment"`
+	ARMEndpoint      types.String `tfsdk:"arm_endpoint"`
+
+	MaxRetries    types.Int64  `tfsdk:"max_retries"`
+	MaxRetryDelay types.String `tfsdk:"max_retry_delay"`
+}
+
+type subnetCidrReservationModel struct {
+	Backend types.String `tfsdk:"backend"`
+	Path    types.String `tfsdk:"path"`
 }
 
 // New creates a new provider instance.
@@ -68,19 +112,202 @@ func (p *dxProvider) Schema(ctx context.Context, req provider.SchemaRequest, res
 				Optional:    true,
 				Description: "Location where the resources will be deployed",
 				Validators: []validator.String{
-					stringvalidator.OneOf([]string{"weu", "itn", "westeurope", "italynorth"}...),
+					stringvalidator.OneOfCaseInsensitive(supportedLocations()...),
+				},
+			},
+			"custom_resource_types": customResourceTypesAttribute(),
+			"subnet_cidr_reservation": schema.SingleNestedAttribute{
+				Optional:    true,
+				Description: "Persists the CIDR blocks allocated by dx_available_subnet_cidr and dx_available_subnet_cidrs, so they are visible to every Terraform run and not only to the workspace state",
+				Attributes: map[string]schema.Attribute{
+					"backend": schema.StringAttribute{
+						Required:    true,
+						Description: "Where reservations are stored: virtual_network_tags (tags on the VNet) or local_file",
+						Validators: []validator.String{
+							stringvalidator.OneOf(reservationBackendVirtualNetworkTags, reservationBackendLocalFile),
+						},
+					},
+					"path": schema.StringAttribute{
+						Optional:    true,
+						Description: "Path of the JSON file holding the reservations, required by the local_file backend",
+					},
 				},
 			},
+			"use_cli": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Authenticate to Azure with the Azure CLI. Can also be set with the ARM_USE_CLI environment variable. Defaults to true",
+			},
+			"use_msi": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Authenticate to Azure with a managed identity, the user-assigned one of client_id if set. Can also be set with the ARM_USE_MSI environment variable",
+			},
+			"use_oidc": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Authenticate to Azure with an OIDC token, read from oidc_token_file_path or requested to GitHub Actions. Can also be set with the ARM_USE_OIDC environment variable",
+			},
+			"client_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Client ID of the identity to authenticate as, required by OIDC. Can also be set with the ARM_CLIENT_ID environment variable",
+			},
+			"tenant_id": schema.StringAttribute{
+				Optional:    true,
+				Description: "Microsoft Entra tenant ID, required by OIDC. Can also be set with the ARM_TENANT_ID environment variable",
+			},
+			"azure_environment": schema.StringAttribute{
+				Optional:    true,
+				Description: "Azure cloud to connect to: public, usgovernment or china. Can also be set with the ARM_ENVIRONMENT environment variable. Defaults to public",
+				Validators: []validator.String{
+					stringvalidator.OneOf(azureEnvironmentPublic, azureEnvironmentUSGovernment, azureEnvironmentChina),
+				},
+			},
+			"arm_endpoint": schema.StringAttribute{
+				Optional:    true,
+				Description: "URL of the Azure Resource Manager endpoint, replacing the one of azure_environment, e.g. for a private cloud. http is only allowed on the local machine",
+			},
+			"max_retries": schema.Int64Attribute{
+				Optional:    true,
+				Description: "Maximum number of retries of a throttled or failed Azure request. Defaults to 3",
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"max_retry_delay": schema.StringAttribute{
+				Optional:    true,
+				Description: "Maximum delay between retries, e.g. 30s or 2m. Requests whose Retry-After is longer fail without waiting. Defaults to 60s",
+			},
+			"oidc_token_file_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path of the file holding the OIDC token, e.g. the one of AKS workload identity. Can also be set with the ARM_OIDC_TOKEN_FILE_PATH environment variable",
+			},
 		},
 	}
 }
 
+func (p *dxProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var config dxProviderModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateCustomResourceTypesConfig(ctx, config.CustomResourceTypes)...)
+
+	if _, err := newRetryConfig(config.MaxRetries, config.MaxRetryDelay); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("max_retry_delay"), "Invalid retry configuration", err.Error())
+	}
+
+	if !config.SubnetCidrReservation.IsNull() && !config.SubnetCidrReservation.IsUnknown() {
+		var reservation subnetCidrReservationModel
+		resp.Diagnostics.Append(config.SubnetCidrReservation.As(ctx, &reservation, basetypes.ObjectAsOptions{})...)
+		if reservation.Backend.ValueString() == reservationBackendLocalFile && reservation.Path.IsNull() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("subnet_cidr_reservation").AtName("path"),
+				"Missing reservations file path",
+				"The local_file backend requires the path attribute",
+			)
+		}
+	}
+}
+
 func (p *dxProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var config dxProviderModel
 	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	custom, diags := customResourceTypesFromConfig(ctx, config.CustomResourceTypes)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	abbreviations, err := extendResourceAbbreviations(custom)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("custom_resource_types"), "Invalid custom resource type", err.Error())
+		return
+	}
+
+	network, diags := p.networkClient(config)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	reservationStore, diags := newReservationStore(ctx, config.SubnetCidrReservation, network)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	providerData := &dxProviderData{
+		resourceAbbreviations: abbreviations,
+		reservationStore:      reservationStore,
+		network:               network,
+		cidrPreviews:          newCidrPreviews(),
+	}
+	resp.DataSourceData = providerData
+	resp.ResourceData = providerData
+}
+
+// networkClient builds the Azure SDK clients with the credential and the cloud of the configuration
+func (p *dxProvider) networkClient(config dxProviderModel) (networkClient, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if p.network != nil {
+		return p.network, diags
+	}
+
+	auth, err := newAzureAuthConfig(config)
+	if err != nil {
+		diags.AddError("Invalid Azure authentication configuration", err.Error())
+		return nil, diags
+	}
+
+	cloudConfig, err := azureCloudConfiguration(config.AzureEnvironment, config.ARMEndpoint)
+	if err != nil {
+		diags.AddError("Invalid Azure cloud configuration", err.Error())
+		return nil, diags
+	}
+
+	retry, err := newRetryConfig(config.MaxRetries, config.MaxRetryDelay)
+	if err != nil {
+		diags.AddAttributeError(path.Root("max_retry_delay"), "Invalid retry configuration", err.Error())
+		return nil, diags
+	}
+
+	credential, err := newAzureCredential(auth, cloudConfig)
+	if err != nil {
+		diags.AddError(
+			"Azure Authentication Failed",
+			fmt.Sprintf("Unable to create Azure credential: %s", err),
+		)
+		return nil, diags
+	}
+
+	return &azureClients{credential: credential, options: armClientOptions(cloudConfig, retry)}, diags
+}
+
+// newReservationStore returns the reservation store of the subnet_cidr_reservation attribute, nil when not set
+func newReservationStore(ctx context.Context, value types.Object, network networkClient) (cidrReservationStore, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	if value.IsNull() || value.IsUnknown() {
+		return nil, diags
+	}
+
+	var reservation subnetCidrReservationModel
+	diags.Append(value.As(ctx, &reservation, basetypes.ObjectAsOptions{})...)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	switch reservation.Backend.ValueString() {
+	case reservationBackendVirtualNetworkTags:
+		return newVNetTagReservationStore(network), diags
+	case reservationBackendLocalFile:
+		return newFileReservationStore(reservation.Path.ValueString()), diags
+	default:
+		return nil, diags
+	}
 }
 
 // Resources
@@ -88,13 +315,17 @@ func (p *dxProvider) Configure(ctx context.Context, req provider.ConfigureReques
 func (p *dxProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewAvailableSubnetCidrResource,
+		NewAvailableSubnetCidrsResource,
 	}
 }
 
 // DataSources
 
 func (p *dxProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
-	return nil
+	return []func() datasource.DataSource{
+		NewResourceTypesDataSource,
+		NewVirtualNetworkFreeSpaceDataSource,
+	}
 }
 
 // Functions
@@ -104,6 +335,8 @@ func (p *dxProvider) Functions(_ context.Context) []func() function.Function {
 		NewResourceNameFunction,
 		NewConvertLocationToLongFormatFunction,
 		NewConvertLocationToShortFormatFunction,
+		NewParseResourceNameFunction,
+		NewResourceNamesFunction,
 	}
 }
 
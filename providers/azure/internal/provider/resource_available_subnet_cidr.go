@@ -3,17 +3,17 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"regexp"
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
-	"github.com/apparentlymart/go-cidr/cidr"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -35,6 +35,12 @@ func NewAvailableSubnetCidrResource() resource.Resource {
 
 // Resource definition
 type availableSubnetCidrResource struct {
+	// reservations persists the allocated blocks across runs, nil when not configured
+	reservations cidrReservationStore
+	// network reads the Virtual Networks, with the credential and cloud configured in the provider
+	network networkClient
+	// previews holds the blocks previewed by the other resources of the plan
+	previews *cidrPreviews
 }
 
 // Resource model
@@ -43,8 +49,23 @@ type availableSubnetCidrResourceModel struct {
 	VirtualNetworkID types.String `tfsdk:"virtual_network_id"`
 	PrefixLength     types.Int64  `tfsdk:"prefix_length"`
 	CidrBlock        types.String `tfsdk:"cidr_block"`
+	Ipv6PrefixLength types.Int64  `tfsdk:"ipv6_prefix_length"`
+	Ipv6CidrBlock    types.String `tfsdk:"ipv6_cidr_block"`
+	Status           types.String `tfsdk:"status"`
+	Strategy         types.String `tfsdk:"strategy"`
+	Offset           types.Int64  `tfsdk:"offset"`
+	WithinCidr       types.String `tfsdk:"within_cidr"`
+	ExcludeCidrs     types.Set    `tfsdk:"exclude_cidrs"`
+	PreviewInPlan    types.Bool   `tfsdk:"preview_in_plan"`
 }
 
+// Values of the status attribute, set by Read comparing the block with the current VNet
+const (
+	cidrStatusOK                  = "ok"
+	cidrStatusOverlapping         = "overlapping"
+	cidrStatusOutsideAddressSpace = "outside_address_space"
+)
+
 func (r *availableSubnetCidrResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
 	resp.TypeName = req.ProviderTypeName + "_available_subnet_cidr"
 }
@@ -75,8 +96,8 @@ func (r *availableSubnetCidrResource) Schema(ctx context.Context, req resource.S
 				},
 			},
 			"prefix_length": schema.Int64Attribute{
-				Description: "The desired prefix length for the new subnet CIDR (e.g., 24 for /24). Must be larger than the VNet prefix and smaller or equal to 29.",
-				Required:    true,
+				Description: "The desired prefix length for the new subnet IPv4 CIDR (e.g., 24 for /24). Must be larger than the VNet prefix and smaller or equal to 29. At least one of prefix_length and ipv6_prefix_length must be set.",
+				Optional:    true,
 				Validators: []validator.Int64{
 					int64validator.Between(1, 29), // Azure subnet limits
 				},
@@ -93,12 +114,79 @@ func (r *availableSubnetCidrResource) Schema(ctx context.Context, req resource.S
 					stringplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"ipv6_prefix_length": schema.Int64Attribute{
+				Description: "The desired prefix length for the new subnet IPv6 CIDR, allocated from the IPv6 address space of a dual-stack VNet. Azure IPv6 subnets are always /64.",
+				Optional:    true,
+				Validators: []validator.Int64{
+					int64validator.OneOf(64), // Azure IPv6 subnet size
+				},
+				PlanModifiers: []planmodifier.Int64{
+					prefixLengthRequiresReplace(),
+				},
+			},
+			"ipv6_cidr_block": schema.StringAttribute{
+				Description: "The allocated available IPv6 CIDR block, if ipv6_prefix_length is set.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"strategy": schema.StringAttribute{
+				Description: "How the block is placed in the VNet: first_fit (default, the lowest free block), best_fit (the smallest free gap that fits), last_fit (the highest free block, keeping the bottom of the space for large subnets) or aligned (the block at offset). Only used when the block is allocated.",
+				Optional:    true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(cidrStrategies()...),
+				},
+			},
+			"offset": schema.Int64Attribute{
+				Description: "Index of the block to allocate with the aligned strategy, counting blocks of prefix_length from the start of within_cidr, or of the first VNet address prefix (e.g. 1 with a /26 in 10.0.0.0/24 is 10.0.0.64/26). Defaults to 0.",
+				Optional:    true,
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"within_cidr": schema.StringAttribute{
+				Description: "Range of the VNet address space where the block is allocated, in CIDR notation. Defaults to the whole address space.",
+				Optional:    true,
+			},
+			"exclude_cidrs": schema.SetAttribute{
+				Description: "Ranges the block must not overlap, in CIDR notation (e.g. peering ranges or on-premises networks).",
+				Optional:    true,
+				ElementType: types.StringType,
+			},
+			"preview_in_plan": schema.BoolAttribute{
+				Description: "Computes the CIDR blocks while planning, so plans show them instead of (known after apply). The blocks are checked again at apply, which fails if they were taken in the meantime. Defaults to false.",
+				Optional:    true,
+			},
+			"status": schema.StringAttribute{
+				Description: "Whether the allocated block still fits the VNet: ok, overlapping (a subnet other than the one using this block overlaps it) or outside_address_space (the VNet address space no longer contains it).",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
 		},
 	}
 }
 
-// Configure prepares the Azure client
+// Configure reads the reservation store and the network client configured in the provider
 func (r *availableSubnetCidrResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.reservations = providerData.reservationStore
+	r.network = providerData.network
+	r.previews = providerData.cidrPreviews
 }
 
 // ValidateConfig validates the resource configuration
@@ -123,11 +211,25 @@ func (r *availableSubnetCidrResource) ValidateConfig(ctx context.Context, req re
 		}
 	}
 
+	if data.PrefixLength.IsNull() && data.Ipv6PrefixLength.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("prefix_length"),
+			"Missing Prefix Length",
+			"At least one of prefix_length and ipv6_prefix_length must be set",
+		)
+	}
+
+	resp.Diagnostics.Append(validateAllocationOptions(ctx, data)...)
+
 	// Note: It is not possible to verify if prefix_length is modified here
 	// because ValidateConfigRequest does not have access to the state.
 	// Instead, we use RequiresReplace in the planmodifier.
 }
 
+// cidrReservationAttempts bounds the blocks tried by Create when other runs keep reserving the
+// block it picked first
+const cidrReservationAttempts = 5
+
 // Create allocates a new CIDR block from the available pool
 func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data availableSubnetCidrResourceModel
@@ -138,30 +240,115 @@ func (r *availableSubnetCidrResource) Create(ctx context.Context, req resource.C
 		return
 	}
 
-	// Find an available CIDR block
-	cidrBlock, diags := findAvailableCidrBlock(ctx, data.VirtualNetworkID.ValueString(), int(data.PrefixLength.ValueInt64()))
+	// Serialise allocations on the same VNet, so that resources created in parallel
+	// don't pick the same block before any subnet exists
+	allocations, unlock := subnetCidrAllocator.lock(data.VirtualNetworkID.ValueString())
+	defer unlock()
+
+	// Blocks reserved by other Terraform runs are unavailable too
+	persisted, err := reservedCidrBlocks(ctx, r.reservations, data.VirtualNetworkID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"CIDR Reservation Error",
+			fmt.Sprintf("Failed to list the reserved CIDR blocks: %s", err),
+		)
+		return
+	}
+
+	// Find the available CIDR blocks, skipping the ones already handed out
+	reserved := append(allocations.reservedBlocks(), persisted...)
+	options, diags := allocationOptions(ctx, data)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Set the CIDR block and resource ID
-	data.CidrBlock = types.StringValue(cidrBlock)
+	vnetID := data.VirtualNetworkID.ValueString()
+	var allocated []string
+	for _, ipv6 := range []bool{false, true} {
+		prefixLength := data.PrefixLength
+		if ipv6 {
+			prefixLength = data.Ipv6PrefixLength
+		}
+		if prefixLength.IsNull() {
+			continue
+		}
 
-	// Generate a unique ID for the resource
-	// Format: {virtualNetworkId}_{prefixLength}_{cidrBlock}
-	resourceID := fmt.Sprintf("%s_%d_%s",
-		strings.ReplaceAll(data.VirtualNetworkID.ValueString(), "/", "_"),
-		data.PrefixLength.ValueInt64(),
-		strings.ReplaceAll(cidrBlock, "/", "_"))
-	data.ID = types.StringValue(resourceID)
+		options.ipv6 = ipv6
+		previewed := data.CidrBlock
+		if ipv6 {
+			previewed = data.Ipv6CidrBlock
+		}
+
+		var cidrBlock string
+		for attempt := 1; ; attempt++ {
+			// A block previewed in the plan is kept, as long as it is still free
+			cidrBlock = previewed.ValueString()
+			if previewed.IsUnknown() {
+				cidrBlock, diags = findAvailableCidrBlock(ctx, r.network, vnetID, int(prefixLength.ValueInt64()), reserved, options)
+			} else {
+				diags = checkPreviewedCidrBlock(ctx, r.network, vnetID, cidrBlock, reserved, options)
+			}
+			resp.Diagnostics.Append(diags...)
+			if resp.Diagnostics.HasError() {
+				resp.Diagnostics.Append(releaseLockedCidrBlocks(ctx, r.reservations, allocations, vnetID, allocated)...)
+				return
+			}
+
+			err := reserveCidrBlock(ctx, r.reservations, allocations, vnetID, cidrBlock)
+			if err == nil {
+				break
+			}
+
+			// Another run reserved the block since the reservations were listed: pick another one,
+			// unless the block was previewed in the plan
+			if errors.Is(err, errCidrBlockReserved) && previewed.IsUnknown() && attempt < cidrReservationAttempts {
+				tflog.Debug(ctx, "CIDR block reserved concurrently, allocating another one", map[string]interface{}{"cidr_block": cidrBlock})
+				_, block, _ := net.ParseCIDR(cidrBlock)
+				reserved = append(reserved, block)
+				continue
+			}
+
+			resp.Diagnostics.Append(releaseLockedCidrBlocks(ctx, r.reservations, allocations, vnetID, allocated)...)
+			if errors.Is(err, errCidrBlockReserved) && !previewed.IsUnknown() {
+				resp.Diagnostics.AddError(
+					"Previewed CIDR Block Unavailable",
+					fmt.Sprintf("The CIDR block %s previewed in the plan was reserved by another run in the meantime, run terraform plan again to preview another block.", cidrBlock),
+				)
+				return
+			}
+			resp.Diagnostics.AddError(
+				"CIDR Reservation Error",
+				fmt.Sprintf("Failed to reserve CIDR block '%s': %s", cidrBlock, err),
+			)
+			return
+		}
+		allocated = append(allocated, cidrBlock)
+
+		if ipv6 {
+			data.Ipv6CidrBlock = types.StringValue(cidrBlock)
+		} else {
+			data.CidrBlock = types.StringValue(cidrBlock)
+		}
+	}
+
+	// Set the CIDR blocks and resource ID
+	if data.CidrBlock.IsUnknown() {
+		data.CidrBlock = types.StringNull()
+	}
+	if data.Ipv6CidrBlock.IsUnknown() {
+		data.Ipv6CidrBlock = types.StringNull()
+	}
+	data.Status = types.StringValue(cidrStatusOK)
+	data.ID = types.StringValue(availableSubnetCidrID(data))
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 	tflog.Info(ctx, "Created available subnet CIDR resource", map[string]interface{}{
 		"virtual_network_id": data.VirtualNetworkID.ValueString(),
 		"prefix_length":      data.PrefixLength.ValueInt64(),
-		"cidr_block":         cidrBlock,
+		"cidr_block":         data.CidrBlock.ValueString(),
+		"ipv6_cidr_block":    data.Ipv6CidrBlock.ValueString(),
 	})
 }
 
@@ -175,9 +362,65 @@ func (r *availableSubnetCidrResource) Read(ctx context.Context, req resource.Rea
 		return
 	}
 
-	// This is a virtual resource, so we don't need to read anything from Azure.
-	// The resource represents a reserved CIDR block, which is tracked only in Terraform state.
-	// We simply keep the existing values.
+	// The block itself is tracked only in Terraform state, but the VNet may have changed
+	// since it was allocated: check it still fits, so broken plans surface before the
+	// subnet creation fails
+	parsedID, err := parseVNetID(data.VirtualNetworkID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Virtual Network ID",
+			fmt.Sprintf("Cannot parse VNet ID '%s': %s", data.VirtualNetworkID.ValueString(), err),
+		)
+		return
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, r.network, parsedID)
+	if isAzureNotFound(err) {
+		resp.Diagnostics.AddWarning(
+			"Virtual Network Not Found",
+			fmt.Sprintf("Virtual Network '%s' no longer exists, CIDR blocks %s are removed from state.", parsedID.vnetName, strings.Join(allocatedBlocks(data), ", ")),
+		)
+		for _, cidrBlock := range allocatedBlocks(data) {
+			subnetCidrAllocator.release(data.VirtualNetworkID.ValueString(), cidrBlock)
+		}
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		// Drift detection is best effort, an unreachable VNet must not break the plan
+		resp.Diagnostics.AddWarning(
+			"Subnet CIDR Drift Not Checked",
+			fmt.Sprintf("Unable to read Virtual Network '%s', keeping the prior state: %s", parsedID.vnetName, err),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	// The status reports the first drifted block, each one gets a warning
+	status := cidrStatusOK
+	for _, cidrBlock := range allocatedBlocks(data) {
+		_, block, err := net.ParseCIDR(cidrBlock)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Parse Error",
+				fmt.Sprintf("Failed to parse CIDR '%s' from state: %s", cidrBlock, err),
+			)
+			return
+		}
+
+		blockStatus, detail := cidrBlockStatus(block, layout.addressPrefixes, layout.subnets)
+		if blockStatus == cidrStatusOK {
+			continue
+		}
+		resp.Diagnostics.AddWarning(
+			"Subnet CIDR Drift Detected",
+			fmt.Sprintf("CIDR block '%s' of Virtual Network '%s' %s.", block, parsedID.vnetName, detail),
+		)
+		if status == cidrStatusOK {
+			status = blockStatus
+		}
+	}
+	data.Status = types.StringValue(status)
 
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -203,38 +446,225 @@ func (r *availableSubnetCidrResource) Update(ctx context.Context, req resource.U
 
 // Delete deletes the resource
 func (r *availableSubnetCidrResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
-	// This is a virtual resource, nothing to actually delete in Azure
-	// Just write a log message and return without attempting to read the state
+	var data availableSubnetCidrResourceModel
+
+	// This is a virtual resource, nothing to actually delete in Azure.
+	// Only forget the block if it was handed out by this provider process.
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(releaseCidrBlocks(ctx, r.reservations, data.VirtualNetworkID.ValueString(), allocatedBlocks(data))...)
+
 	tflog.Info(ctx, "Deleting available subnet CIDR resource")
 }
 
 // ModifyPlan implements the plan modifiers for this resource
 func (r *availableSubnetCidrResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
-	// In the creation phase, we don't modify the plan
-	// Leave the cidr_block attribute as "known after apply"
-	// This prevents inconsistency errors during apply
-	tflog.Info(ctx, "CIDR value will be calculated during apply")
+	// Only new blocks are checked or previewed, existing ones are kept in state
+	if !req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data availableSubnetCidrResourceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() || data.VirtualNetworkID.IsUnknown() {
+		return
+	}
+
+	// A block already known in the plan is kept, Create checks it is still free
+	if len(allocatedBlocks(data)) > 0 {
+		return
+	}
+
+	parsedID, err := parseVNetID(data.VirtualNetworkID.ValueString())
+	if err != nil {
+		return // Reported by ValidateConfig
+	}
+
+	// A within_cidr outside the VNet would only fail at apply, check it while planning a new block
+	if !data.WithinCidr.IsNull() && !data.WithinCidr.IsUnknown() {
+		resp.Diagnostics.Append(validateWithinCidr(ctx, r.network, parsedID, data.WithinCidr.ValueString())...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	if !data.PreviewInPlan.ValueBool() {
+		// Leave the cidr_block attribute as "known after apply"
+		tflog.Info(ctx, "CIDR value will be calculated during apply")
+		return
+	}
+
+	if !previewable(data) {
+		tflog.Debug(ctx, "Skipping CIDR preview, the configuration has unknown values")
+		return
+	}
+	r.previewCidrBlocks(ctx, &data, resp)
 }
 
-// createAzureCredential returns an Azure credential, preferring Azure CLI and falling back to DefaultAzureCredential.
-func createAzureCredential(ctx context.Context) (azcore.TokenCredential, diag.Diagnostics) {
+// validateWithinCidr checks within_cidr is inside the VNet address space. The VNet may not exist
+// yet, or not be readable while planning: the check is then left to apply.
+func validateWithinCidr(ctx context.Context, network networkClient, parsedID *parsedVNetID, withinCidr string) diag.Diagnostics {
 	var diagnostics diag.Diagnostics
 
-	cliCred, err := azidentity.NewAzureCLICredential(nil)
+	_, within, err := net.ParseCIDR(withinCidr)
+	if err != nil {
+		return diagnostics // Reported by ValidateConfig
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, network, parsedID)
 	if err != nil {
-		tflog.Warn(ctx, "Azure CLI credential failed, falling back to DefaultAzureCredential", map[string]interface{}{"error": err.Error()})
+		tflog.Debug(ctx, "Skipping within_cidr validation", map[string]interface{}{"error": err.Error()})
+		return diagnostics
+	}
 
-		defaultCred, err := azidentity.NewDefaultAzureCredential(nil)
-		if err != nil {
-			diagnostics.AddError(
-				"Azure Authentication Failed",
-				fmt.Sprintf("Unable to create Azure credential with any method: %s", err),
+	if !cidrWithinAddressSpace(within, layout.addressPrefixes) {
+		diagnostics.AddAttributeError(
+			path.Root("within_cidr"),
+			"Range Outside Virtual Network",
+			fmt.Sprintf("within_cidr %s is not inside the address space of Virtual Network '%s'", within, parsedID.vnetName),
+		)
+	}
+	return diagnostics
+}
+
+// previewCidrBlocks sets the blocks Create would allocate in the plan. Terraform plans the resource
+// again right before applying it and requires the same blocks, so the preview only depends on the
+// VNet subnets and the persisted reservations, never on the blocks handed out by this process.
+// Failures leave the blocks unknown, to be allocated during apply. Two resources of the plan
+// previewing overlapping blocks fail the plan: leaving the second block unknown would not do, as
+// the resources are planned again in any order during apply.
+func (r *availableSubnetCidrResource) previewCidrBlocks(ctx context.Context, data *availableSubnetCidrResourceModel, resp *resource.ModifyPlanResponse) {
+	vnetID := data.VirtualNetworkID.ValueString()
+
+	persisted, err := reservedCidrBlocks(ctx, r.reservations, vnetID)
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"CIDR Preview Unavailable",
+			fmt.Sprintf("Failed to list the reserved CIDR blocks, the blocks will be allocated during apply: %s", err),
+		)
+		return
+	}
+
+	options, diags := allocationOptions(ctx, *data)
+	if diags.HasError() {
+		return // Reported by ValidateConfig
+	}
+
+	previews := map[bool]types.String{false: types.StringNull(), true: types.StringNull()}
+	var blocks []*net.IPNet
+	for _, ipv6 := range []bool{false, true} {
+		prefixLength := data.PrefixLength
+		if ipv6 {
+			prefixLength = data.Ipv6PrefixLength
+		}
+		if prefixLength.IsNull() {
+			continue
+		}
+
+		options.ipv6 = ipv6
+		cidrBlock, diags := findAvailableCidrBlock(ctx, r.network, vnetID, int(prefixLength.ValueInt64()), persisted, options)
+		if diags.HasError() {
+			for _, d := range diags.Errors() {
+				resp.Diagnostics.AddWarning(
+					"CIDR Preview Unavailable",
+					fmt.Sprintf("%s: %s. The blocks will be allocated during apply.", d.Summary(), d.Detail()),
+				)
+			}
+			return
+		}
+		previews[ipv6] = types.StringValue(cidrBlock)
+		_, block, _ := net.ParseCIDR(cidrBlock)
+		blocks = append(blocks, block)
+	}
+
+	if r.previews != nil {
+		if previewed := r.previews.claim(vnetID, blocks); previewed != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Preview Collision",
+				fmt.Sprintf("The CIDR block %s is previewed for another resource on VNet '%s' in this plan, only one of them could get it. "+
+					"Keep the previewed resources apart with disjoint within_cidr ranges or aligned offsets, allocate the blocks together with dx_available_subnet_cidrs, or disable preview_in_plan.", previewed, vnetID),
 			)
-			return nil, diagnostics
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("cidr_block"), previews[false])...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ipv6_cidr_block"), previews[true])...)
+	tflog.Info(ctx, "Previewed CIDR blocks", map[string]interface{}{
+		"cidr_block":      previews[false].ValueString(),
+		"ipv6_cidr_block": previews[true].ValueString(),
+	})
+}
+
+// previewable reports whether all the attributes driving the allocation are known
+func previewable(data availableSubnetCidrResourceModel) bool {
+	for _, value := range []attr.Value{data.PrefixLength, data.Ipv6PrefixLength, data.Strategy, data.Offset, data.WithinCidr, data.ExcludeCidrs} {
+		if value.IsUnknown() {
+			return false
 		}
-		return defaultCred, diagnostics
 	}
-	return cliCred, diagnostics
+	for _, value := range data.ExcludeCidrs.Elements() {
+		if value.IsUnknown() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPreviewedCidrBlock checks a block previewed in the plan can still be allocated: it must be
+// in the VNet address space and within_cidr, and not overlap the subnets, the reserved nor the
+// excluded blocks
+func checkPreviewedCidrBlock(ctx context.Context, network networkClient, vnetID, cidrBlock string, reserved []*net.IPNet, options cidrAllocationOptions) diag.Diagnostics {
+	var diagnostics diag.Diagnostics
+
+	_, block, err := net.ParseCIDR(cidrBlock)
+	if err != nil {
+		diagnostics.AddError(
+			"CIDR Parse Error",
+			fmt.Sprintf("Failed to parse the previewed CIDR '%s': %s", cidrBlock, err),
+		)
+		return diagnostics
+	}
+
+	parsedID, err := parseVNetID(vnetID)
+	if err != nil {
+		diagnostics.AddError(
+			"Invalid Virtual Network ID",
+			fmt.Sprintf("Cannot parse VNet ID '%s': %s", vnetID, err),
+		)
+		return diagnostics
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, network, parsedID)
+	if err != nil {
+		diagnostics.Append(azureAPIErrorDiagnostic(err))
+		return diagnostics
+	}
+
+	var reason string
+	switch {
+	case !cidrWithinAddressSpace(block, layout.addressPrefixes):
+		reason = "is no longer inside the VNet address space"
+	case options.within != nil && (options.within.IP.To4() == nil) == options.ipv6 && !cidrWithinAddressSpace(block, []*net.IPNet{options.within}):
+		reason = "is not inside within_cidr"
+	case overlapsAny(block, layout.subnetBlocks()):
+		reason = "overlaps a subnet created in the meantime"
+	case overlapsAny(block, reserved):
+		reason = "was handed out to another resource in the meantime"
+	case overlapsAny(block, options.exclude):
+		reason = "overlaps exclude_cidrs"
+	default:
+		return diagnostics
+	}
+
+	diagnostics.AddError(
+		"Previewed CIDR Block Unavailable",
+		fmt.Sprintf("The CIDR block %s previewed in the plan %s, run terraform plan again to preview another block.", cidrBlock, reason),
+	)
+	return diagnostics
 }
 
 // ImportState imports an existing subnet CIDR reservation into Terraform state.
@@ -254,36 +684,46 @@ func (r *availableSubnetCidrResource) ImportState(ctx context.Context, req resou
 		return
 	}
 
-	cred, diags := createAzureCredential(ctx)
-	resp.Diagnostics.Append(diags...)
-	if resp.Diagnostics.HasError() {
+	subnet, err := r.network.getSubnet(ctx, subnetInfo)
+	if err != nil {
+		resp.Diagnostics.Append(azureAPIErrorDiagnostic(err))
 		return
 	}
 
-	subnetClient, err := armnetwork.NewSubnetsClient(subnetInfo.subscriptionID, cred, nil)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Azure Client Creation Failed",
-			fmt.Sprintf("Unable to create Azure Subnets client: %s", err),
-		)
-		return
+	var prefixes []*string
+	if subnet.Properties != nil {
+		if subnet.Properties.AddressPrefix != nil {
+			prefixes = append(prefixes, subnet.Properties.AddressPrefix)
+		}
+		prefixes = append(prefixes, subnet.Properties.AddressPrefixes...)
 	}
 
-	subnet, err := subnetClient.Get(ctx, subnetInfo.resourceGroupName, subnetInfo.vnetName, subnetInfo.subnetName, nil)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Azure API Error",
-			fmt.Sprintf("Failed to get subnet '%s' in VNet '%s': %s", subnetInfo.subnetName, subnetInfo.vnetName, err),
-		)
-		return
+	vnetID := subnetInfo.vnetID()
+	data := availableSubnetCidrResourceModel{ExcludeCidrs: types.SetNull(types.StringType)}
+	data.VirtualNetworkID = types.StringValue(vnetID)
+
+	// Dual-stack subnets have an IPv4 and an IPv6 prefix, keep the first of each
+	for _, prefix := range prefixes {
+		_, ipnet, err := net.ParseCIDR(*prefix)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Parse Error",
+				fmt.Sprintf("Failed to parse CIDR '%s' returned by Azure: %s", *prefix, err),
+			)
+			return
+		}
+		prefixLen, _ := ipnet.Mask.Size()
+
+		if ipnet.IP.To4() == nil && data.Ipv6CidrBlock.IsNull() {
+			data.Ipv6PrefixLength = types.Int64Value(int64(prefixLen))
+			data.Ipv6CidrBlock = types.StringValue(*prefix)
+		} else if ipnet.IP.To4() != nil && data.CidrBlock.IsNull() {
+			data.PrefixLength = types.Int64Value(int64(prefixLen))
+			data.CidrBlock = types.StringValue(*prefix)
+		}
 	}
 
-	var cidrBlock string
-	if subnet.Properties != nil && subnet.Properties.AddressPrefix != nil {
-		cidrBlock = *subnet.Properties.AddressPrefix
-	} else if subnet.Properties != nil && len(subnet.Properties.AddressPrefixes) > 0 {
-		cidrBlock = *subnet.Properties.AddressPrefixes[0]
-	} else {
+	if data.CidrBlock.IsNull() && data.Ipv6CidrBlock.IsNull() {
 		resp.Diagnostics.AddError(
 			"Subnet Configuration Error",
 			fmt.Sprintf("Subnet '%s' has no address prefix defined.", subnetInfo.subnetName),
@@ -291,39 +731,169 @@ func (r *availableSubnetCidrResource) ImportState(ctx context.Context, req resou
 		return
 	}
 
-	_, ipnet, err := net.ParseCIDR(cidrBlock)
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"CIDR Parse Error",
-			fmt.Sprintf("Failed to parse CIDR '%s' returned by Azure: %s", cidrBlock, err),
-		)
-		return
-	}
-	prefixLen, _ := ipnet.Mask.Size()
-
-	vnetID := subnetInfo.vnetID()
-	resourceID := fmt.Sprintf("%s_%d_%s",
-		strings.ReplaceAll(vnetID, "/", "_"),
-		prefixLen,
-		strings.ReplaceAll(cidrBlock, "/", "_"))
-
-	var data availableSubnetCidrResourceModel
-	data.ID = types.StringValue(resourceID)
-	data.VirtualNetworkID = types.StringValue(vnetID)
-	data.PrefixLength = types.Int64Value(int64(prefixLen))
-	data.CidrBlock = types.StringValue(cidrBlock)
+	data.ID = types.StringValue(availableSubnetCidrID(data))
+	data.Status = types.StringValue(cidrStatusOK)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 	tflog.Info(ctx, "Imported available subnet CIDR resource", map[string]interface{}{
 		"subnet_id":          req.ID,
 		"virtual_network_id": vnetID,
-		"prefix_length":      prefixLen,
-		"cidr_block":         cidrBlock,
+		"prefix_length":      data.PrefixLength.ValueInt64(),
+		"cidr_block":         data.CidrBlock.ValueString(),
+		"ipv6_cidr_block":    data.Ipv6CidrBlock.ValueString(),
 	})
 }
 
-// Helper function to find an available CIDR block
-func findAvailableCidrBlock(ctx context.Context, vnetID string, prefixLength int) (string, diag.Diagnostics) {
+// availableSubnetCidrID generates a unique ID for the resource from its IPv4 block, or from
+// the IPv6 block when only that is allocated.
+// Format: {virtualNetworkId}_{prefixLength}_{cidrBlock}
+func availableSubnetCidrID(data availableSubnetCidrResourceModel) string {
+	prefixLength, cidrBlock := data.PrefixLength, data.CidrBlock
+	if cidrBlock.IsNull() {
+		prefixLength, cidrBlock = data.Ipv6PrefixLength, data.Ipv6CidrBlock
+	}
+
+	return fmt.Sprintf("%s_%d_%s",
+		strings.ReplaceAll(data.VirtualNetworkID.ValueString(), "/", "_"),
+		prefixLength.ValueInt64(),
+		strings.ReplaceAll(cidrBlock.ValueString(), "/", "_"))
+}
+
+// allocatedBlocks returns the IPv4 and IPv6 blocks held by the resource
+func allocatedBlocks(data availableSubnetCidrResourceModel) []string {
+	var cidrBlocks []string
+	for _, cidrBlock := range []types.String{data.CidrBlock, data.Ipv6CidrBlock} {
+		if !cidrBlock.IsNull() && !cidrBlock.IsUnknown() && cidrBlock.ValueString() != "" {
+			cidrBlocks = append(cidrBlocks, cidrBlock.ValueString())
+		}
+	}
+	return cidrBlocks
+}
+
+// validateAllocationOptions checks strategy, offset, within_cidr and exclude_cidrs are consistent
+func validateAllocationOptions(ctx context.Context, data availableSubnetCidrResourceModel) diag.Diagnostics {
+	_, diagnostics := allocationOptions(ctx, data)
+	return diagnostics
+}
+
+// allocationOptions converts the placement attributes of the model. Unknown values are skipped,
+// so the same checks run at validation time and at apply.
+func allocationOptions(ctx context.Context, data availableSubnetCidrResourceModel) (cidrAllocationOptions, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+	options := cidrAllocationOptions{
+		strategy: data.Strategy.ValueString(),
+		offset:   int(data.Offset.ValueInt64()),
+	}
+
+	if !data.Offset.IsNull() && !data.Strategy.IsUnknown() && data.Strategy.ValueString() != cidrStrategyAligned {
+		diagnostics.AddAttributeError(
+			path.Root("offset"),
+			"Offset Requires Aligned Strategy",
+			fmt.Sprintf("offset is only used by the %s strategy", cidrStrategyAligned),
+		)
+	}
+
+	if !data.ExcludeCidrs.IsNull() && !data.ExcludeCidrs.IsUnknown() {
+		var excluded []types.String
+		diagnostics.Append(data.ExcludeCidrs.ElementsAs(ctx, &excluded, false)...)
+		for _, value := range excluded {
+			if value.IsUnknown() {
+				continue
+			}
+			block, diags := parseCidrAttribute(path.Root("exclude_cidrs"), value.ValueString())
+			diagnostics.Append(diags...)
+			if block != nil {
+				options.exclude = append(options.exclude, block)
+			}
+		}
+	}
+
+	if data.WithinCidr.IsNull() || data.WithinCidr.IsUnknown() {
+		return options, diagnostics
+	}
+
+	within, diags := parseCidrAttribute(path.Root("within_cidr"), data.WithinCidr.ValueString())
+	diagnostics.Append(diags...)
+	if within == nil {
+		return options, diagnostics
+	}
+	options.within = within
+
+	// within_cidr restricts the block of its own IP version
+	prefixLengthAttribute, prefixLengthValue := "prefix_length", data.PrefixLength
+	if within.IP.To4() == nil {
+		prefixLengthAttribute, prefixLengthValue = "ipv6_prefix_length", data.Ipv6PrefixLength
+	}
+	if prefixLengthValue.IsNull() {
+		diagnostics.AddAttributeError(
+			path.Root("within_cidr"),
+			"Range Without Block",
+			fmt.Sprintf("within_cidr %s restricts the block sized by %s, which is not set", within, prefixLengthAttribute),
+		)
+		return options, diagnostics
+	}
+	if prefixLengthValue.IsUnknown() {
+		return options, diagnostics
+	}
+	withinPrefixLen, _ := within.Mask.Size()
+	prefixLength := int(prefixLengthValue.ValueInt64())
+	if prefixLength < withinPrefixLen {
+		diagnostics.AddAttributeError(
+			path.Root(prefixLengthAttribute),
+			"Prefix Length Too Small",
+			fmt.Sprintf("A /%d block doesn't fit within_cidr %s", prefixLength, within),
+		)
+		return options, diagnostics
+	}
+	if !data.Offset.IsNull() && !data.Offset.IsUnknown() && !offsetInRange(data.Offset.ValueInt64(), prefixLength-withinPrefixLen) {
+		diagnostics.AddAttributeError(
+			path.Root("offset"),
+			"Offset Out Of Range",
+			fmt.Sprintf("within_cidr %s holds %d blocks of /%d, offset must be lower", within, int64(1)<<uint(prefixLength-withinPrefixLen), prefixLength),
+		)
+	}
+
+	return options, diagnostics
+}
+
+// parseCidrAttribute parses a range set in an attribute, rejecting host bits so the range is unambiguous
+func parseCidrAttribute(attributePath path.Path, value string) (*net.IPNet, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+
+	_, block, err := net.ParseCIDR(value)
+	if err != nil {
+		diagnostics.AddAttributeError(
+			attributePath,
+			"Invalid CIDR",
+			fmt.Sprintf("%s must be in CIDR notation: %s", attributePath, err),
+		)
+		return nil, diagnostics
+	}
+	if block.String() != value {
+		diagnostics.AddAttributeError(
+			attributePath,
+			"Invalid CIDR",
+			fmt.Sprintf("%s %s has host bits set, did you mean %s?", attributePath, value, block),
+		)
+		return nil, diagnostics
+	}
+	return block, diagnostics
+}
+
+// cidrWithinAddressSpace reports whether a range is inside one of the address prefixes
+func cidrWithinAddressSpace(within *net.IPNet, addressPrefixes []*net.IPNet) bool {
+	withinPrefixLen, _ := within.Mask.Size()
+	for _, addressPrefix := range addressPrefixes {
+		prefixLen, _ := addressPrefix.Mask.Size()
+		if prefixLen <= withinPrefixLen && addressPrefix.Contains(within.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to find an available CIDR block, not overlapping existing subnets nor the reserved blocks
+func findAvailableCidrBlock(ctx context.Context, network networkClient, vnetID string, prefixLength int, reserved []*net.IPNet, options cidrAllocationOptions) (string, diag.Diagnostics) {
 	var diagnostics diag.Diagnostics
 
 	// --- Parsing VNet ID ---
@@ -336,154 +906,172 @@ func findAvailableCidrBlock(ctx context.Context, vnetID string, prefixLength int
 		return "", diagnostics
 	}
 
-	// --- Setup Azure Client with Fallback ---
-	cred, diags := createAzureCredential(ctx)
-	diagnostics.Append(diags...)
-	if diagnostics.HasError() {
+	// --- Get VNet Details and Existing Subnets ---
+	layout, err := getVirtualNetworkLayout(ctx, network, parsedID)
+	if err != nil {
+		diagnostics.Append(azureAPIErrorDiagnostic(err))
 		return "", diagnostics
 	}
 
-	// --- Get VNet Details ---
-	vnetClient, err := armnetwork.NewVirtualNetworksClient(parsedID.subscriptionID, cred, nil)
-	if err != nil {
+	if len(layout.addressPrefixes) == 0 {
 		diagnostics.AddError(
-			"Azure Client Creation Failed",
-			fmt.Sprintf("Unable to create Azure VirtualNetworks client: %s", err),
+			"VNet Configuration Error",
+			fmt.Sprintf("Virtual Network '%s' has no address spaces defined.", parsedID.vnetName),
 		)
 		return "", diagnostics
 	}
 
-	vnetResp, err := vnetClient.Get(ctx, parsedID.resourceGroupName, parsedID.vnetName, nil)
-	if err != nil {
+	if options.ipv6 && !hasIPv6Prefix(layout.addressPrefixes) {
 		diagnostics.AddError(
-			"Azure API Error",
-			fmt.Sprintf("Failed to get Virtual Network '%s': %s", parsedID.vnetName, err),
+			"VNet Configuration Error",
+			fmt.Sprintf("Virtual Network '%s' has no IPv6 address space.", parsedID.vnetName),
 		)
 		return "", diagnostics
 	}
 
-	if vnetResp.Properties == nil || vnetResp.Properties.AddressSpace == nil || len(vnetResp.Properties.AddressSpace.AddressPrefixes) == 0 {
+	existingSubnetCIDRs := layout.subnetBlocks()
+
+	// Blocks handed out but not yet materialised as subnets are unavailable too
+	existingSubnetCIDRs = append(existingSubnetCIDRs, reserved...)
+
+	// --- Find Available CIDR ---
+	foundCidr := selectAvailableCidrBlock(ctx, layout.rawAddressPrefixes, existingSubnetCIDRs, prefixLength, options)
+	if foundCidr == "" && options.strategy == cidrStrategyAligned {
 		diagnostics.AddError(
-			"VNet Configuration Error",
-			fmt.Sprintf("Virtual Network '%s' has no address spaces defined.", parsedID.vnetName),
+			"CIDR Calculation Failed",
+			fmt.Sprintf("The /%d CIDR block at offset %d is outside the address space of VNet %s or already in use", prefixLength, options.offset, vnetID),
 		)
 		return "", diagnostics
 	}
-
-	vnetAddressPrefixes := vnetResp.Properties.AddressSpace.AddressPrefixes
-
-	// --- Get Existing Subnets ---
-	subnetClient, err := armnetwork.NewSubnetsClient(parsedID.subscriptionID, cred, nil)
-	if err != nil {
+	if foundCidr == "" {
 		diagnostics.AddError(
-			"Azure Client Creation Failed",
-			fmt.Sprintf("Unable to create Azure Subnets client: %s", err),
+			"CIDR Calculation Failed",
+			fmt.Sprintf("Could not find an available /%d CIDR block in VNet %s", prefixLength, vnetID),
 		)
 		return "", diagnostics
 	}
 
-	existingSubnetCIDRs := []*net.IPNet{}
+	return foundCidr, diagnostics
+}
 
-	pager := subnetClient.NewListPager(parsedID.resourceGroupName, parsedID.vnetName, nil)
-	for pager.More() {
-		page, err := pager.NextPage(ctx)
-		if err != nil {
-			diagnostics.AddError(
-				"Azure API Error",
-				fmt.Sprintf("Failed to list subnets for VNet '%s': %s", parsedID.vnetName, err),
-			)
-			return "", diagnostics
-		}
+// virtualNetworkLayout holds the address space and the subnets of a VNet
+type virtualNetworkLayout struct {
+	// rawAddressPrefixes are the address prefixes as returned by Azure
+	rawAddressPrefixes []*string
+	addressPrefixes    []*net.IPNet
+	subnets            []subnetLayout
+}
 
-		for _, subnet := range page.Value {
-			if subnet.Properties != nil && subnet.Properties.AddressPrefix != nil {
-				_, ipnet, err := net.ParseCIDR(*subnet.Properties.AddressPrefix)
-				if err != nil {
-					tflog.Warn(ctx, "Could not parse existing subnet CIDR", map[string]interface{}{"cidr": *subnet.Properties.AddressPrefix, "error": err.Error()})
-					continue
-				}
-				existingSubnetCIDRs = append(existingSubnetCIDRs, ipnet)
-			}
+// subnetLayout holds the address prefixes of a subnet
+type subnetLayout struct {
+	name     string
+	prefixes []*net.IPNet
+}
 
-			if subnet.Properties != nil && subnet.Properties.AddressPrefixes != nil && len(subnet.Properties.AddressPrefixes) > 0 {
-				for _, prefix := range subnet.Properties.AddressPrefixes {
-					_, ipnet, err := net.ParseCIDR(*prefix)
-					if err != nil {
-						tflog.Warn(ctx, "Could not parse existing subnet CIDR from list", map[string]interface{}{"cidr": *prefix, "error": err.Error()})
-						continue
-					}
-					existingSubnetCIDRs = append(existingSubnetCIDRs, ipnet)
-				}
-			}
-		}
+// subnetBlocks returns the address prefixes of all the subnets
+func (l *virtualNetworkLayout) subnetBlocks() []*net.IPNet {
+	blocks := []*net.IPNet{}
+	for _, subnet := range l.subnets {
+		blocks = append(blocks, subnet.prefixes...)
 	}
+	return blocks
+}
 
-	// --- Find Available CIDR ---
-	desiredPrefixLen := prefixLength
-	foundCidr := ""
+// getVirtualNetworkLayout reads the address space and the subnets of a VNet.
+// Errors wrap the Azure response, so a missing VNet can be told apart with isAzureNotFound.
+func getVirtualNetworkLayout(ctx context.Context, network networkClient, parsedID *parsedVNetID) (*virtualNetworkLayout, error) {
+	vnetResp, err := network.getVirtualNetwork(ctx, parsedID)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, vnetPrefixPtr := range vnetAddressPrefixes {
-		vnetPrefix := *vnetPrefixPtr
-		_, vnetNet, err := net.ParseCIDR(vnetPrefix)
+	layout := &virtualNetworkLayout{}
+	if vnetResp.Properties != nil && vnetResp.Properties.AddressSpace != nil {
+		layout.rawAddressPrefixes = vnetResp.Properties.AddressSpace.AddressPrefixes
+	}
+	for _, prefix := range layout.rawAddressPrefixes {
+		_, ipnet, err := net.ParseCIDR(*prefix)
 		if err != nil {
-			tflog.Warn(ctx, "Could not parse VNet address prefix", map[string]interface{}{"cidr": vnetPrefix, "error": err})
+			tflog.Warn(ctx, "Could not parse VNet address prefix", map[string]interface{}{"cidr": *prefix, "error": err.Error()})
 			continue
 		}
+		layout.addressPrefixes = append(layout.addressPrefixes, ipnet)
+	}
+
+	subnets, err := network.listSubnets(ctx, parsedID)
+	if err != nil {
+		return nil, err
+	}
 
-		vnetPrefixLen, _ := vnetNet.Mask.Size()
-		if desiredPrefixLen <= vnetPrefixLen {
-			tflog.Warn(ctx, "Desired prefix length too small", map[string]interface{}{
-				"desired_length": desiredPrefixLen,
-				"vnet_length":    vnetPrefixLen,
-				"vnet_prefix":    vnetPrefix,
-			})
+	for _, subnet := range subnets {
+		if subnet.Properties == nil {
 			continue
 		}
 
-		subnetCount := 1 << uint(desiredPrefixLen-vnetPrefixLen)
-		tflog.Debug(ctx, "Possible subnet count in VNet space", map[string]interface{}{
-			"vnet_prefix":    vnetPrefix,
-			"vnet_length":    vnetPrefixLen,
-			"desired_length": desiredPrefixLen,
-			"subnet_count":   subnetCount,
-		})
+		var prefixes []*string
+		if subnet.Properties.AddressPrefix != nil {
+			prefixes = append(prefixes, subnet.Properties.AddressPrefix)
+		}
+		prefixes = append(prefixes, subnet.Properties.AddressPrefixes...)
 
-		for i := 0; i < subnetCount; i++ {
-			candidateNet, err := cidr.Subnet(vnetNet, desiredPrefixLen-vnetPrefixLen, i)
+		current := subnetLayout{}
+		if subnet.Name != nil {
+			current.name = *subnet.Name
+		}
+		for _, prefix := range prefixes {
+			_, ipnet, err := net.ParseCIDR(*prefix)
 			if err != nil {
-				tflog.Warn(ctx, "Error calculating subnet CIDR", map[string]interface{}{"error": err.Error()})
+				tflog.Warn(ctx, "Could not parse existing subnet CIDR", map[string]interface{}{"cidr": *prefix, "error": err.Error()})
 				continue
 			}
+			current.prefixes = append(current.prefixes, ipnet)
+		}
+		layout.subnets = append(layout.subnets, current)
+	}
 
-			overlaps := false
-			for _, existingNet := range existingSubnetCIDRs {
-				if cidrOverlaps(candidateNet, existingNet) {
-					overlaps = true
-					break
-				}
-			}
+	return layout, nil
+}
 
-			if !overlaps {
-				foundCidr = candidateNet.String()
-				tflog.Info(ctx, "Found available CIDR", map[string]interface{}{"cidr": foundCidr})
-				break
-			}
+// hasIPv6Prefix reports whether a dual-stack address space has an IPv6 prefix
+func hasIPv6Prefix(addressPrefixes []*net.IPNet) bool {
+	for _, addressPrefix := range addressPrefixes {
+		if addressPrefix.IP.To4() == nil {
+			return true
 		}
+	}
+	return false
+}
 
-		if foundCidr != "" {
-			break
-		}
+// isAzureNotFound reports whether an error comes from an Azure response with status 404
+func isAzureNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// isAzurePreconditionFailed reports whether an error comes from an Azure response with status 412,
+// returned when the If-Match ETag of a request no longer matches the resource
+func isAzurePreconditionFailed(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// cidrBlockStatus checks an allocated block against the current VNet layout. The block must still
+// be within the address space, and may only overlap a subnet using exactly that block, which is
+// the subnet it was allocated for. The detail describes a status other than ok.
+func cidrBlockStatus(block *net.IPNet, addressPrefixes []*net.IPNet, subnets []subnetLayout) (string, string) {
+	if !cidrWithinAddressSpace(block, addressPrefixes) {
+		return cidrStatusOutsideAddressSpace, "is no longer within the address space of the VNet"
 	}
 
-	if foundCidr == "" {
-		diagnostics.AddError(
-			"CIDR Calculation Failed",
-			fmt.Sprintf("Could not find an available /%d CIDR block in VNet %s", desiredPrefixLen, vnetID),
-		)
-		return "", diagnostics
+	for _, subnet := range subnets {
+		for _, prefix := range subnet.prefixes {
+			if prefix.String() != block.String() && cidrOverlaps(prefix, block) {
+				return cidrStatusOverlapping, fmt.Sprintf("overlaps subnet '%s' (%s)", subnet.name, prefix)
+			}
+		}
 	}
 
-	return foundCidr, diagnostics
+	return cidrStatusOK, ""
 }
 
 // prefixLengthRequiresReplace is a plan modifier that requires recreating the resource
@@ -506,8 +1094,8 @@ func (m *prefixLengthRequiresReplaceModifier) MarkdownDescription(ctx context.Co
 
 // PlanModifyInt64 implements the plan modifier logic
 func (m *prefixLengthRequiresReplaceModifier) PlanModifyInt64(ctx context.Context, req planmodifier.Int64Request, resp *planmodifier.Int64Response) {
-	// If we don't have a previous state value, there's nothing to do
-	if req.StateValue.IsNull() {
+	// If the resource is being created, there's nothing to do
+	if req.State.Raw.IsNull() {
 		return
 	}
 
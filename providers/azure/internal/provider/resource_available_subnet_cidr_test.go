@@ -0,0 +1,580 @@
+package provider
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func mustParseCIDR(t *testing.T, value string) *net.IPNet {
+	t.Helper()
+
+	_, ipnet, err := net.ParseCIDR(value)
+	if err != nil {
+		t.Fatalf("invalid CIDR %s: %s", value, err)
+	}
+	return ipnet
+}
+
+func TestCidrBlockStatus(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := []*net.IPNet{mustParseCIDR(t, "10.0.0.0/16")}
+
+	tests := []struct {
+		name         string
+		block        string
+		addressSpace []*net.IPNet
+		subnets      []subnetLayout
+		expected     string
+	}{
+		{
+			name:         "free block",
+			block:        "10.0.1.0/24",
+			addressSpace: addressSpace,
+			subnets:      []subnetLayout{{name: "other", prefixes: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/24")}}},
+			expected:     cidrStatusOK,
+		},
+		{
+			name:         "block used by its own subnet",
+			block:        "10.0.1.0/24",
+			addressSpace: addressSpace,
+			subnets:      []subnetLayout{{name: "ours", prefixes: []*net.IPNet{mustParseCIDR(t, "10.0.1.0/24")}}},
+			expected:     cidrStatusOK,
+		},
+		{
+			name:         "block overlapping another subnet",
+			block:        "10.0.1.0/24",
+			addressSpace: addressSpace,
+			subnets:      []subnetLayout{{name: "other", prefixes: []*net.IPNet{mustParseCIDR(t, "10.0.0.0/23")}}},
+			expected:     cidrStatusOverlapping,
+		},
+		{
+			name:         "VNet re-addressed",
+			block:        "10.0.1.0/24",
+			addressSpace: []*net.IPNet{mustParseCIDR(t, "10.1.0.0/16")},
+			expected:     cidrStatusOutsideAddressSpace,
+		},
+		{
+			name:         "block larger than the address space",
+			block:        "10.0.0.0/15",
+			addressSpace: addressSpace,
+			expected:     cidrStatusOutsideAddressSpace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, detail := cidrBlockStatus(mustParseCIDR(t, tt.block), tt.addressSpace, tt.subnets)
+			if status != tt.expected {
+				t.Errorf("expected status %s, got %s (%s)", tt.expected, status, detail)
+			}
+		})
+	}
+}
+
+func TestPreviewable(t *testing.T) {
+	t.Parallel()
+
+	known := availableSubnetCidrResourceModel{
+		PrefixLength:     types.Int64Value(24),
+		Ipv6PrefixLength: types.Int64Null(),
+		Strategy:         types.StringNull(),
+		Offset:           types.Int64Null(),
+		WithinCidr:       types.StringValue("10.0.16.0/20"),
+		ExcludeCidrs:     types.SetValueMust(types.StringType, []attr.Value{types.StringValue("10.0.31.0/24")}),
+	}
+	if !previewable(known) {
+		t.Error("expected a known configuration to be previewable")
+	}
+
+	unknownWithin := known
+	unknownWithin.WithinCidr = types.StringUnknown()
+	if previewable(unknownWithin) {
+		t.Error("expected an unknown within_cidr not to be previewable")
+	}
+
+	unknownExclude := known
+	unknownExclude.ExcludeCidrs = types.SetValueMust(types.StringType, []attr.Value{types.StringUnknown()})
+	if previewable(unknownExclude) {
+		t.Error("expected an unknown exclude_cidrs element not to be previewable")
+	}
+}
+
+func TestAvailableSubnetCidrResource_Lifecycle(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-lifecycle", "10.0.0.0/16")
+	fake.addSubnet(vnetID, "default", "10.0.0.0/24")
+	fake.addSubnet(vnetID, "apps", "10.0.1.0/24")
+	fake.addSubnet(vnetID, "data", "10.0.3.0/24")
+
+	config := fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+}
+`, vnetID)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.0.2.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "status", cidrStatusOK),
+					resource.TestCheckNoResourceAttr("dx_available_subnet_cidr.test", "ipv6_cidr_block"),
+				),
+			},
+			{
+				// A subnet created outside of Terraform over the block is reported by Read
+				PreConfig: func() { fake.addSubnet(vnetID, "intruder", "10.0.2.128/25") },
+				Config:    config,
+				Check:     resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "status", cidrStatusOverlapping),
+			},
+			{
+				ResourceName:  "dx_available_subnet_cidr.test",
+				ImportState:   true,
+				ImportStateId: vnetID + "/subnets/apps",
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 imported resource, got %d", len(states))
+					}
+					for name, expected := range map[string]string{
+						"virtual_network_id": vnetID,
+						"prefix_length":      "24",
+						"cidr_block":         "10.0.1.0/24",
+						"status":             cidrStatusOK,
+					} {
+						if got := states[0].Attributes[name]; got != expected {
+							return fmt.Errorf("expected %s %s, got %s", name, expected, got)
+						}
+					}
+					return nil
+				},
+			},
+			{
+				ResourceName:  "dx_available_subnet_cidr.test",
+				ImportState:   true,
+				ImportStateId: vnetID + "/subnets/missing",
+				ExpectError:   regexp.MustCompile(`Subnet 'missing' not found`),
+			},
+			{
+				// The resource is removed from state with its VNet, so a new block is planned
+				PreConfig:          func() { fake.deleteVirtualNetwork(vnetID) },
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_Count(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-count", "10.2.0.0/24")
+
+	config := func(count int) string {
+		return fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  count              = %d
+  virtual_network_id = %q
+  prefix_length      = 26
+}
+`, count, vnetID)
+	}
+
+	// blocks records the block of each instance, so later steps can compare with it
+	blocks := map[string]string{}
+	recordBlocks := func(count int) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			seen := map[string]string{}
+			for i := 0; i < count; i++ {
+				name := fmt.Sprintf("dx_available_subnet_cidr.test.%d", i)
+				rs, ok := s.RootModule().Resources[name]
+				if !ok {
+					return fmt.Errorf("%s not found in state", name)
+				}
+				block := rs.Primary.Attributes["cidr_block"]
+				if other, ok := seen[block]; ok {
+					return fmt.Errorf("%s and %s were both allocated %s", other, name, block)
+				}
+				seen[block] = name
+				blocks[name] = block
+			}
+			return nil
+		}
+	}
+
+	var released string
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// Instances are created in parallel, each one gets its own block
+				Config: config(3),
+				Check:  recordBlocks(3),
+			},
+			{
+				Config: config(2),
+				Check: func(*terraform.State) error {
+					released = blocks["dx_available_subnet_cidr.test.2"]
+					return nil
+				},
+			},
+			{
+				// The block of the destroyed instance was released, so the new one reuses it
+				Config: config(3),
+				Check: resource.ComposeTestCheckFunc(
+					recordBlocks(3),
+					func(*terraform.State) error {
+						if got := blocks["dx_available_subnet_cidr.test.2"]; got != released {
+							return fmt.Errorf("expected the released block %s to be reused, got %s", released, got)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_WithinCidr(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-within", "10.4.0.0/16")
+	fake.addSubnet(vnetID, "default", "10.4.0.0/24")
+	fake.addSubnet(vnetID, "apps", "10.4.16.0/24")
+
+	config := func(withinCidr string) string {
+		return fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+  within_cidr        = %q
+}
+`, vnetID, withinCidr)
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// The VNet is read while planning, so the range is rejected before apply
+				Config:      config("10.5.0.0/20"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`within_cidr 10.5.0.0/20 is not inside the address space of Virtual Network[\s\n]+'vnet-within'`),
+			},
+			{
+				Config:      config("10.4.16.0/25"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`A /24 block doesn't fit within_cidr 10.4.16.0/25`),
+			},
+			{
+				// The first free /24 of the VNet is 10.4.1.0/24, the first one of the range is 10.4.17.0/24
+				Config: config("10.4.16.0/20"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.4.17.0/24"),
+					func(s *terraform.State) error {
+						block := mustParseCIDR(t, s.RootModule().Resources["dx_available_subnet_cidr.test"].Primary.Attributes["cidr_block"])
+						if !cidrWithinAddressSpace(block, []*net.IPNet{mustParseCIDR(t, "10.4.16.0/20")}) {
+							return fmt.Errorf("expected %s to be inside within_cidr 10.4.16.0/20", block)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_LargeIPv6AddressSpace(t *testing.T) {
+	t.Parallel()
+
+	// A /32 holds 2^32 blocks of /64, which are never enumerated one by one
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-ipv6", "10.6.0.0/16", "fd00:db8::/32")
+	fake.addSubnet(vnetID, "default", "10.6.0.0/24", "fd00:db8::/64")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "first" {
+  virtual_network_id = %[1]q
+  prefix_length      = 24
+  ipv6_prefix_length = 64
+}
+
+resource "dx_available_subnet_cidr" "last" {
+  virtual_network_id = %[1]q
+  ipv6_prefix_length = 64
+  strategy           = "last_fit"
+}
+`, vnetID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.first", "cidr_block", "10.6.1.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.first", "ipv6_cidr_block", "fd00:db8:0:1::/64"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.last", "ipv6_cidr_block", "fd00:db8:ffff:ffff::/64"),
+				),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_AuthorizationFailed(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-denied", "10.0.0.0/16")
+	fake.deny()
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+}
+`, vnetID),
+				ExpectError: regexp.MustCompile(`Azure Authorization Failed(.|\n)*Microsoft.Network/virtualNetworks/subnets/read`),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_VirtualNetworkTagsReservation(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-tags", "10.1.0.0/24")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "dx" {
+  subnet_cidr_reservation = {
+    backend = "virtual_network_tags"
+  }
+}
+
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 26
+}
+`, vnetID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.1.0.0/26"),
+					func(*terraform.State) error {
+						if _, ok := fake.tags(vnetID)["dx-subnet-cidr-10.1.0.0_26"]; !ok {
+							return fmt.Errorf("expected the block to be reserved in the VNet tags, got %v", fake.tags(vnetID))
+						}
+						return nil
+					},
+				),
+			},
+		},
+		CheckDestroy: func(*terraform.State) error {
+			if tags := fake.tags(vnetID); len(tags) != 0 {
+				return fmt.Errorf("expected the reservation to be released, got %v", tags)
+			}
+			return nil
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_ReservationConflict(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-conflict", "10.3.0.0/24")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// Another run reserves the first free block after it was listed, so another one is picked
+				PreConfig: func() {
+					fake.writeConcurrently(map[string]string{"dx-subnet-cidr-10.3.0.0_26": "2026-01-01T00:00:00Z"})
+				},
+				Config: fmt.Sprintf(`
+provider "dx" {
+  subnet_cidr_reservation = {
+    backend = "virtual_network_tags"
+  }
+}
+
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 26
+}
+`, vnetID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.3.0.64/26"),
+					func(*terraform.State) error {
+						tags := fake.tags(vnetID)
+						for _, name := range []string{"dx-subnet-cidr-10.3.0.0_26", "dx-subnet-cidr-10.3.0.64_26"} {
+							if _, ok := tags[name]; !ok {
+								return fmt.Errorf("expected tag %s, got %v", name, tags)
+							}
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_PreviewInPlan(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-preview", "10.7.0.0/16")
+	fake.addSubnet(vnetID, "default", "10.7.0.0/24")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// Both resources on the VNet are previewed, and get the previewed blocks at apply
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "apps" {
+  virtual_network_id = %[1]q
+  prefix_length      = 24
+  preview_in_plan    = true
+}
+
+resource "dx_available_subnet_cidr" "pep" {
+  virtual_network_id = %[1]q
+  prefix_length      = 27
+  within_cidr        = "10.7.255.0/24"
+  preview_in_plan    = true
+}
+`, vnetID),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectKnownValue("dx_available_subnet_cidr.apps", tfjsonpath.New("cidr_block"), knownvalue.StringExact("10.7.1.0/24")),
+						plancheck.ExpectKnownValue("dx_available_subnet_cidr.pep", tfjsonpath.New("cidr_block"), knownvalue.StringExact("10.7.255.0/27")),
+					},
+				},
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.apps", "cidr_block", "10.7.1.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidr.pep", "cidr_block", "10.7.255.0/27"),
+				),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_PreviewCollision(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-preview-collision", "10.8.0.0/16")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// Both resources would preview the first free block, only one of them could get it
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  count              = 2
+  virtual_network_id = %q
+  prefix_length      = 24
+  preview_in_plan    = true
+}
+`, vnetID),
+				ExpectError: regexp.MustCompile(`CIDR Preview Collision(.|\n)*10\.8\.0\.0/24 is previewed for another resource`),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_PreviewedCidrBlockTaken(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-preview-taken", "10.9.0.0/16")
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// The VNet is read to preview the block, to plan it again during apply, then by
+				// Create, which finds the subnet created between the two on the previewed block
+				PreConfig: func() {
+					fake.addSubnetOnRead(vnetID, 3, "taken", "10.9.0.0/24")
+				},
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+  preview_in_plan    = true
+}
+`, vnetID),
+				ExpectError: regexp.MustCompile(`The CIDR block 10\.9\.0\.0/24 previewed in the plan overlaps a subnet created in[\s\n]+the meantime`),
+			},
+		},
+	})
+}
+
+func TestAvailableSubnetCidrResource_PreviewedCidrBlockTakenBeforeApply(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-preview-replan", "10.11.0.0/16")
+	config := fmt.Sprintf(`
+resource "dx_available_subnet_cidr" "test" {
+  virtual_network_id = %q
+  prefix_length      = 24
+  preview_in_plan    = true
+}
+`, vnetID)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				// Terraform plans the resource again during apply without the planned values, so a
+				// subnet created on the previewed block before then changes the preview, which
+				// Terraform rejects before Create runs
+				PreConfig: func() {
+					fake.addSubnetOnRead(vnetID, 2, "taken", "10.11.0.0/24")
+				},
+				Config:      config,
+				ExpectError: regexp.MustCompile(`Provider produced inconsistent final plan(.|\n)*was[\s\n]+cty\.StringVal\("10\.11\.0\.0/24"\), but now[\s\n]+cty\.StringVal\("10\.11\.1\.0/24"\)`),
+			},
+			{
+				// Nothing was reserved, planning again previews the next free block
+				Config: config,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectKnownValue("dx_available_subnet_cidr.test", tfjsonpath.New("cidr_block"), knownvalue.StringExact("10.11.1.0/24")),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("dx_available_subnet_cidr.test", "cidr_block", "10.11.1.0/24"),
+			},
+		},
+	})
+}
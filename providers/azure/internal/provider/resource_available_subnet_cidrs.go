@@ -0,0 +1,596 @@
+// Implementation of the resource to allocate several available CIDR blocks for subnets in Azure at once
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/mapvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Make sure it implements the Resource, ResourceWithConfigure, ResourceWithModifyPlan and ResourceWithImportState interfaces
+var _ resource.Resource = &availableSubnetCidrsResource{}
+var _ resource.ResourceWithConfigure = &availableSubnetCidrsResource{}
+var _ resource.ResourceWithModifyPlan = &availableSubnetCidrsResource{}
+var _ resource.ResourceWithImportState = &availableSubnetCidrsResource{}
+
+// subnetNamePattern matches the Azure subnet names, which can't contain the separators of the resource ID
+var subnetNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]{0,78}[A-Za-z0-9_])?$`)
+
+func NewAvailableSubnetCidrsResource() resource.Resource {
+	return &availableSubnetCidrsResource{}
+}
+
+// Resource definition
+type availableSubnetCidrsResource struct {
+	// reservations persists the allocated blocks across runs, nil when not configured
+	reservations cidrReservationStore
+	// network reads the Virtual Networks, with the credential and cloud configured in the provider
+	network networkClient
+}
+
+// Resource model
+type availableSubnetCidrsResourceModel struct {
+	ID               types.String `tfsdk:"id"`
+	VirtualNetworkID types.String `tfsdk:"virtual_network_id"`
+	PrefixLengths    types.Map    `tfsdk:"prefix_lengths"`
+	CidrBlocks       types.Map    `tfsdk:"cidr_blocks"`
+}
+
+func (r *availableSubnetCidrsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_available_subnet_cidrs"
+}
+
+func (r *availableSubnetCidrsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Allocates and reserves several available CIDR blocks for new subnets within a specified Azure Virtual Network, all at once or none.",
+
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "Resource identifier, the VNet ID and the allocated blocks (e.g. <vnet ID>|apps=10.0.1.0/24,pep=10.0.2.0/27)",
+				Computed:    true,
+			},
+			"virtual_network_id": schema.StringAttribute{
+				Description: "The Azure Resource ID of the Virtual Network.",
+				Required:    true,
+				Validators: []validator.String{
+					stringvalidator.RegexMatches(
+						regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourcegroups/[^/]+/providers/microsoft\.network/virtualnetworks/[^/]+$`),
+						"must be a valid Azure VNet resource ID in the format /subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{vnetName}",
+					),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"prefix_lengths": schema.MapAttribute{
+				Description: "The desired prefix length of each subnet, keyed by subnet name (e.g. { apps = 24, pep = 27 }), which must be a valid Azure subnet name. Each must be larger than the VNet prefix and smaller or equal to 29. Adding, removing or resizing a subnet keeps the blocks of the other ones.",
+				Required:    true,
+				ElementType: types.Int64Type,
+				Validators: []validator.Map{
+					mapvalidator.SizeAtLeast(1),
+					mapvalidator.KeysAre(stringvalidator.RegexMatches(subnetNamePattern, "must be a valid subnet name: letters, numbers, underscores, periods and hyphens, starting with a letter or number and ending with a letter, number or underscore")),
+					mapvalidator.ValueInt64sAre(int64validator.Between(1, 29)), // Azure subnet limits
+				},
+			},
+			"cidr_blocks": schema.MapAttribute{
+				Description: "The allocated CIDR blocks, keyed by subnet name.",
+				Computed:    true,
+				ElementType: types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+// Configure reads the reservation store and the network client configured in the provider
+func (r *availableSubnetCidrsResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	providerData, ok := req.ProviderData.(*dxProviderData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *dxProviderData, got: %T", req.ProviderData),
+		)
+		return
+	}
+
+	r.reservations = providerData.reservationStore
+	r.network = providerData.network
+}
+
+// Create allocates all the CIDR blocks in a single pass over the VNet
+func (r *availableSubnetCidrsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data availableSubnetCidrsResourceModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	prefixLengths := map[string]int64{}
+	resp.Diagnostics.Append(data.PrefixLengths.ElementsAs(ctx, &prefixLengths, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vnetID := data.VirtualNetworkID.ValueString()
+	parsedID, err := parseVNetID(vnetID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Virtual Network ID",
+			fmt.Sprintf("Cannot parse VNet ID '%s': %s", vnetID, err),
+		)
+		return
+	}
+
+	// Same locking and reservations as dx_available_subnet_cidr, so both resources can share a VNet
+	allocations, unlock := subnetCidrAllocator.lock(vnetID)
+	defer unlock()
+
+	persisted, err := reservedCidrBlocks(ctx, r.reservations, vnetID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"CIDR Reservation Error",
+			fmt.Sprintf("Failed to list the reserved CIDR blocks: %s", err),
+		)
+		return
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, r.network, parsedID)
+	if err != nil {
+		resp.Diagnostics.Append(azureAPIErrorDiagnostic(err))
+		return
+	}
+
+	used := append(layout.subnetBlocks(), allocations.reservedBlocks()...)
+	used = append(used, persisted...)
+	cidrBlocks, diags := r.allocate(ctx, allocations, vnetID, layout.rawAddressPrefixes, used, prefixLengths)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.CidrBlocks, diags = types.MapValueFrom(ctx, types.StringType, cidrBlocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.ID = types.StringValue(availableSubnetCidrsID(vnetID, cidrBlocks))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	tflog.Info(ctx, "Created available subnet CIDRs resource", map[string]interface{}{
+		"virtual_network_id": vnetID,
+		"cidr_blocks":        cidrBlocks,
+	})
+}
+
+// allocate allocates and reserves a block for each prefix length, all of them or none. When another
+// run reserves one of the blocks first, the blocks are allocated again without it. The caller must
+// hold the lock of allocations.
+func (r *availableSubnetCidrsResource) allocate(ctx context.Context, allocations *networkAllocations, vnetID string, addressPrefixes []*string, used []*net.IPNet, prefixLengths map[string]int64) (map[string]string, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+
+	for attempt := 1; ; attempt++ {
+		cidrBlocks, err := allocateSubnetCidrBlocks(ctx, addressPrefixes, used, prefixLengths)
+		if err != nil {
+			diagnostics.AddError(
+				"CIDR Calculation Failed",
+				fmt.Sprintf("Could not allocate the subnets in VNet %s, none was allocated: %s", vnetID, err),
+			)
+			return nil, diagnostics
+		}
+
+		var allocated []string
+		var failed string
+		for _, name := range sortedKeys(cidrBlocks) {
+			if err = reserveCidrBlock(ctx, r.reservations, allocations, vnetID, cidrBlocks[name]); err != nil {
+				failed = cidrBlocks[name]
+				break
+			}
+			allocated = append(allocated, cidrBlocks[name])
+		}
+		if err == nil {
+			return cidrBlocks, diagnostics
+		}
+		diagnostics.Append(releaseLockedCidrBlocks(ctx, r.reservations, allocations, vnetID, allocated)...)
+
+		// Another run reserved a block since the reservations were listed, allocate them again without it
+		if errors.Is(err, errCidrBlockReserved) && attempt < cidrReservationAttempts {
+			tflog.Debug(ctx, "CIDR block reserved concurrently, allocating the blocks again", map[string]interface{}{"cidr_block": failed})
+			_, block, _ := net.ParseCIDR(failed)
+			used = append(used, block)
+			continue
+		}
+
+		diagnostics.AddError(
+			"CIDR Reservation Error",
+			fmt.Sprintf("Failed to reserve CIDR block '%s', none was allocated: %s", failed, err),
+		)
+		return nil, diagnostics
+	}
+}
+
+// Read checks the allocated blocks still fit the VNet, like dx_available_subnet_cidr does
+func (r *availableSubnetCidrsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data availableSubnetCidrsResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cidrBlocks := map[string]string{}
+	resp.Diagnostics.Append(data.CidrBlocks.ElementsAs(ctx, &cidrBlocks, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	parsedID, err := parseVNetID(data.VirtualNetworkID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid Virtual Network ID",
+			fmt.Sprintf("Cannot parse VNet ID '%s': %s", data.VirtualNetworkID.ValueString(), err),
+		)
+		return
+	}
+
+	layout, err := getVirtualNetworkLayout(ctx, r.network, parsedID)
+	if isAzureNotFound(err) {
+		resp.Diagnostics.AddWarning(
+			"Virtual Network Not Found",
+			fmt.Sprintf("Virtual Network '%s' no longer exists, its CIDR blocks are removed from state.", parsedID.vnetName),
+		)
+		for _, cidrBlock := range cidrBlocks {
+			subnetCidrAllocator.release(data.VirtualNetworkID.ValueString(), cidrBlock)
+		}
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Subnet CIDR Drift Not Checked",
+			fmt.Sprintf("Unable to read Virtual Network '%s', keeping the prior state: %s", parsedID.vnetName, err),
+		)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+		return
+	}
+
+	for _, name := range sortedKeys(cidrBlocks) {
+		_, block, err := net.ParseCIDR(cidrBlocks[name])
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Parse Error",
+				fmt.Sprintf("Failed to parse CIDR '%s' from state: %s", cidrBlocks[name], err),
+			)
+			return
+		}
+
+		if status, detail := cidrBlockStatus(block, layout.addressPrefixes, layout.subnets); status != cidrStatusOK {
+			resp.Diagnostics.AddWarning(
+				"Subnet CIDR Drift Detected",
+				fmt.Sprintf("CIDR block '%s' (%s) of Virtual Network '%s' %s.", block, name, parsedID.vnetName, detail),
+			)
+		}
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// ModifyPlan keeps in the plan the blocks of the subnets whose prefix length is unchanged, the
+// other ones being known after apply
+func (r *availableSubnetCidrsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var state, plan availableSubnetCidrsResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.PrefixLengths.Equal(state.PrefixLengths) {
+		return
+	}
+
+	if plan.PrefixLengths.IsUnknown() {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("cidr_blocks"), types.MapUnknown(types.StringType))...)
+		return
+	}
+
+	kept, _, _, diags := diffPrefixLengths(ctx, state, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	planned := map[string]attr.Value{}
+	for name, value := range plan.PrefixLengths.Elements() {
+		planned[name] = types.StringUnknown()
+		if cidrBlock, ok := kept[name]; ok && !value.IsUnknown() {
+			planned[name] = types.StringValue(cidrBlock)
+		}
+	}
+	cidrBlocks, diags := types.MapValue(types.StringType, planned)
+	resp.Diagnostics.Append(diags...)
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("cidr_blocks"), cidrBlocks)...)
+}
+
+// Update keeps the blocks of the subnets whose prefix length is unchanged, allocates the added or
+// resized ones and releases the removed ones. The new blocks are allocated before releasing the
+// old ones, so a failed update leaves the prior blocks reserved.
+func (r *availableSubnetCidrsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var state, plan availableSubnetCidrsResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cidrBlocks, added, removed, diags := diffPrefixLengths(ctx, state, plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vnetID := plan.VirtualNetworkID.ValueString()
+	allocations, unlock := subnetCidrAllocator.lock(vnetID)
+	defer unlock()
+
+	if len(added) > 0 {
+		parsedID, err := parseVNetID(vnetID)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Invalid Virtual Network ID",
+				fmt.Sprintf("Cannot parse VNet ID '%s': %s", vnetID, err),
+			)
+			return
+		}
+
+		persisted, err := reservedCidrBlocks(ctx, r.reservations, vnetID)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"CIDR Reservation Error",
+				fmt.Sprintf("Failed to list the reserved CIDR blocks: %s", err),
+			)
+			return
+		}
+
+		layout, err := getVirtualNetworkLayout(ctx, r.network, parsedID)
+		if err != nil {
+			resp.Diagnostics.Append(azureAPIErrorDiagnostic(err))
+			return
+		}
+
+		// The prior blocks stay in use until the new ones are reserved
+		used := append(layout.subnetBlocks(), allocations.reservedBlocks()...)
+		used = append(used, persisted...)
+		for _, cidrBlock := range append(sortedValues(cidrBlocks), removed...) {
+			if _, block, err := net.ParseCIDR(cidrBlock); err == nil {
+				used = append(used, block)
+			}
+		}
+
+		allocated, diags := r.allocate(ctx, allocations, vnetID, layout.rawAddressPrefixes, used, added)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		for name, cidrBlock := range allocated {
+			cidrBlocks[name] = cidrBlock
+		}
+	}
+
+	resp.Diagnostics.Append(releaseLockedCidrBlocks(ctx, r.reservations, allocations, vnetID, removed)...)
+
+	plan.CidrBlocks, diags = types.MapValueFrom(ctx, types.StringType, cidrBlocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	plan.ID = types.StringValue(availableSubnetCidrsID(vnetID, cidrBlocks))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	tflog.Info(ctx, "Updated available subnet CIDRs resource", map[string]interface{}{
+		"virtual_network_id": vnetID,
+		"cidr_blocks":        cidrBlocks,
+		"released":           removed,
+	})
+}
+
+// diffPrefixLengths compares the prefix lengths of the state and the plan. It returns the blocks
+// kept by the subnets whose prefix length is unchanged, the prefix lengths of the added or resized
+// subnets, and the blocks of the removed or resized ones.
+func diffPrefixLengths(ctx context.Context, state, plan availableSubnetCidrsResourceModel) (map[string]string, map[string]int64, []string, diag.Diagnostics) {
+	var diagnostics diag.Diagnostics
+
+	priorLengths, priorBlocks, plannedLengths := map[string]int64{}, map[string]string{}, map[string]int64{}
+	diagnostics.Append(state.PrefixLengths.ElementsAs(ctx, &priorLengths, false)...)
+	diagnostics.Append(state.CidrBlocks.ElementsAs(ctx, &priorBlocks, false)...)
+	for name, value := range plan.PrefixLengths.Elements() {
+		if length, ok := value.(types.Int64); ok && !length.IsUnknown() {
+			plannedLengths[name] = length.ValueInt64()
+		}
+	}
+	if diagnostics.HasError() {
+		return nil, nil, nil, diagnostics
+	}
+
+	kept, added := map[string]string{}, map[string]int64{}
+	for name, length := range plannedLengths {
+		if cidrBlock, ok := priorBlocks[name]; ok && priorLengths[name] == length {
+			kept[name] = cidrBlock
+		} else {
+			added[name] = length
+		}
+	}
+
+	var removed []string
+	for _, name := range sortedKeys(priorBlocks) {
+		if _, ok := kept[name]; !ok {
+			removed = append(removed, priorBlocks[name])
+		}
+	}
+	return kept, added, removed, diagnostics
+}
+
+// sortedValues returns the values of a map, sorted by key
+func sortedValues(values map[string]string) []string {
+	sorted := make([]string, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		sorted = append(sorted, values[key])
+	}
+	return sorted
+}
+
+// Delete releases the allocated blocks
+func (r *availableSubnetCidrsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data availableSubnetCidrsResourceModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	cidrBlocks := map[string]string{}
+	resp.Diagnostics.Append(data.CidrBlocks.ElementsAs(ctx, &cidrBlocks, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(releaseCidrBlocks(ctx, r.reservations, data.VirtualNetworkID.ValueString(), sortedValues(cidrBlocks))...)
+
+	tflog.Info(ctx, "Deleting available subnet CIDRs resource")
+}
+
+// ImportState imports the blocks of existing subnets, with an ID in the format of the resource one:
+// the VNet ID and the block of each subnet name, e.g.
+// /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.Network/virtualNetworks/{vnet}|apps=10.0.1.0/24,pep=10.0.2.0/27
+func (r *availableSubnetCidrsResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	vnetID, cidrBlocks, err := parseAvailableSubnetCidrsID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Invalid import ID",
+			fmt.Sprintf(
+				"The import ID must be the Virtual Network ID followed by the CIDR block of each subnet name.\n"+
+					"Format: /subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{vnetName}|{name}={cidr},{name}={cidr}\n\n"+
+					"Got: %s\nError: %s", req.ID, err,
+			),
+		)
+		return
+	}
+
+	prefixLengths := make(map[string]int64, len(cidrBlocks))
+	for name, cidrBlock := range cidrBlocks {
+		_, block, _ := net.ParseCIDR(cidrBlock)
+		prefixLength, _ := block.Mask.Size()
+		prefixLengths[name] = int64(prefixLength)
+	}
+
+	data := availableSubnetCidrsResourceModel{
+		ID:               types.StringValue(availableSubnetCidrsID(vnetID, cidrBlocks)),
+		VirtualNetworkID: types.StringValue(vnetID),
+	}
+	var diags diag.Diagnostics
+	data.PrefixLengths, diags = types.MapValueFrom(ctx, types.Int64Type, prefixLengths)
+	resp.Diagnostics.Append(diags...)
+	data.CidrBlocks, diags = types.MapValueFrom(ctx, types.StringType, cidrBlocks)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	tflog.Info(ctx, "Imported available subnet CIDRs resource", map[string]interface{}{
+		"virtual_network_id": vnetID,
+		"cidr_blocks":        cidrBlocks,
+	})
+}
+
+// availableSubnetCidrsID joins the VNet ID and the blocks sorted by subnet name. Neither subnet
+// names nor blocks contain the separators, and the blocks of a VNet never overlap, so two
+// resources never share an ID.
+func availableSubnetCidrsID(vnetID string, cidrBlocks map[string]string) string {
+	blocks := make([]string, 0, len(cidrBlocks))
+	for _, name := range sortedKeys(cidrBlocks) {
+		blocks = append(blocks, name+"="+cidrBlocks[name])
+	}
+	return vnetID + "|" + strings.Join(blocks, ",")
+}
+
+// parseAvailableSubnetCidrsID parses the ID built by availableSubnetCidrsID
+func parseAvailableSubnetCidrsID(id string) (string, map[string]string, error) {
+	vnetID, blocks, ok := strings.Cut(id, "|")
+	if !ok || blocks == "" {
+		return "", nil, fmt.Errorf("missing the '|' separating the VNet ID from the CIDR blocks")
+	}
+	if _, err := parseVNetID(vnetID); err != nil {
+		return "", nil, err
+	}
+
+	cidrBlocks := map[string]string{}
+	for _, block := range strings.Split(blocks, ",") {
+		name, cidrBlock, ok := strings.Cut(block, "=")
+		if !ok || !subnetNamePattern.MatchString(name) {
+			return "", nil, fmt.Errorf("invalid entry '%s', expected {name}={cidr} with a valid subnet name", block)
+		}
+		if _, ok := cidrBlocks[name]; ok {
+			return "", nil, fmt.Errorf("subnet name '%s' is repeated", name)
+		}
+		_, ipnet, err := net.ParseCIDR(cidrBlock)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid CIDR block of subnet '%s': %w", name, err)
+		}
+		if ipnet.String() != cidrBlock {
+			return "", nil, fmt.Errorf("CIDR block '%s' of subnet '%s' is not a network address, expected %s", cidrBlock, name, ipnet)
+		}
+		cidrBlocks[name] = cidrBlock
+	}
+	return vnetID, cidrBlocks, nil
+}
+
+// allocateSubnetCidrBlocks allocates a block for each named prefix length, or none. Blocks are
+// allocated largest first, which packs them without leaving gaps between different sizes.
+func allocateSubnetCidrBlocks(ctx context.Context, vnetAddressPrefixes []*string, used []*net.IPNet, prefixLengths map[string]int64) (map[string]string, error) {
+	names := sortedKeys(prefixLengths)
+	sort.SliceStable(names, func(i, j int) bool {
+		return prefixLengths[names[i]] < prefixLengths[names[j]]
+	})
+
+	used = append([]*net.IPNet{}, used...)
+	cidrBlocks := make(map[string]string, len(names))
+	for _, name := range names {
+		cidrBlock := selectAvailableCidrBlock(ctx, vnetAddressPrefixes, used, int(prefixLengths[name]), cidrAllocationOptions{})
+		if cidrBlock == "" {
+			return nil, fmt.Errorf("no /%d CIDR block left for subnet '%s'", prefixLengths[name], name)
+		}
+
+		_, block, _ := net.ParseCIDR(cidrBlock)
+		used = append(used, block)
+		cidrBlocks[name] = cidrBlock
+	}
+
+	return cidrBlocks, nil
+}
@@ -0,0 +1,224 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+)
+
+func TestAllocateSubnetCidrBlocks(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := "10.0.0.0/24"
+	used := []*net.IPNet{mustParseCIDR(t, "10.0.0.0/27")}
+
+	got, err := allocateSubnetCidrBlocks(context.Background(), []*string{&addressSpace}, used, map[string]int64{
+		"pep":   28,
+		"apps":  26,
+		"func":  27,
+		"other": 28,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Largest first, ties broken by name
+	expected := map[string]string{
+		"apps":  "10.0.0.64/26",
+		"func":  "10.0.0.32/27",
+		"other": "10.0.0.128/28",
+		"pep":   "10.0.0.144/28",
+	}
+	for name, cidrBlock := range expected {
+		if got[name] != cidrBlock {
+			t.Errorf("%s: expected %q, got %q", name, cidrBlock, got[name])
+		}
+	}
+}
+
+func TestAllocateSubnetCidrBlocks_AllOrNothing(t *testing.T) {
+	t.Parallel()
+
+	addressSpace := "10.0.0.0/24"
+	used := []*net.IPNet{mustParseCIDR(t, "10.0.0.0/25")}
+
+	got, err := allocateSubnetCidrBlocks(context.Background(), []*string{&addressSpace}, used, map[string]int64{
+		"apps": 26,
+		"func": 26,
+		"pep":  28,
+	})
+	if err == nil {
+		t.Fatalf("expected an error, got %v", got)
+	}
+	if got != nil {
+		t.Errorf("expected no block, got %v", got)
+	}
+}
+
+func TestAvailableSubnetCidrsID(t *testing.T) {
+	t.Parallel()
+
+	// Resources on the same VNet with the same prefix lengths get different blocks, so different IDs
+	first := availableSubnetCidrsID(testVNetID, map[string]string{"pep": "10.0.2.0/27", "apps": "10.0.1.0/24"})
+	second := availableSubnetCidrsID(testVNetID, map[string]string{"pep": "10.0.2.32/27", "apps": "10.0.3.0/24"})
+	if first != testVNetID+"|apps=10.0.1.0/24,pep=10.0.2.0/27" || first == second {
+		t.Errorf("unexpected IDs %q and %q", first, second)
+	}
+
+	vnetID, cidrBlocks, err := parseAvailableSubnetCidrsID(first)
+	if err != nil || vnetID != testVNetID || !reflect.DeepEqual(cidrBlocks, map[string]string{"apps": "10.0.1.0/24", "pep": "10.0.2.0/27"}) {
+		t.Errorf("expected the ID to be parsed back, got %q %v (%v)", vnetID, cidrBlocks, err)
+	}
+
+	for _, id := range []string{
+		testVNetID,
+		testVNetID + "|",
+		testVNetID + "|apps",
+		testVNetID + "|apps=10.0.1.0",
+		testVNetID + "|apps=10.0.1.1/24",
+		testVNetID + "|apps=10.0.1.0/24,apps=10.0.2.0/24",
+		"/subscriptions/sub/vnet|apps=10.0.1.0/24",
+	} {
+		if _, _, err := parseAvailableSubnetCidrsID(id); err == nil {
+			t.Errorf("%s: expected an error", id)
+		}
+	}
+}
+
+func TestAvailableSubnetCidrsResource_InvalidPrefixLengths(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		prefixLengths string
+		expectedError string
+	}{
+		{"empty", `{}`, `prefix_lengths map must contain at least 1 elements`},
+		{"too small", `{ apps = 30 }`, `value must be between 1 and 29`},
+		{"invalid subnet name", `{ "apps,pep" = 24 }`, `must be a valid subnet name`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.UnitTest(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidrs" "test" {
+  virtual_network_id = %q
+  prefix_lengths     = %s
+}
+`, testVNetID, tt.prefixLengths),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(tt.expectedError),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAvailableSubnetCidrsResource_Lifecycle(t *testing.T) {
+	t.Parallel()
+
+	fake := newFakeARM(t)
+	vnetID := fake.addVirtualNetwork("vnet-cidrs", "10.10.0.0/22")
+	fake.addSubnet(vnetID, "default", "10.10.0.0/24")
+
+	config := fmt.Sprintf(`
+resource "dx_available_subnet_cidrs" "test" {
+  virtual_network_id = %q
+  prefix_lengths = {
+    apps = 24
+    pep  = 27
+  }
+}
+`, vnetID)
+
+	resource.UnitTest(t, resource.TestCase{
+		ProtoV6ProviderFactories: fake.providerFactories(t),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.apps", "10.10.1.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.pep", "10.10.2.0/27"),
+				),
+			},
+			{
+				// The blocks handed out are not free, even before their subnets exist
+				Config: config + fmt.Sprintf(`
+data "dx_virtual_network_free_space" "test" {
+  virtual_network_id = %q
+  prefix_lengths     = [26]
+}
+`, vnetID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.dx_virtual_network_free_space.test", "free_cidrs.#", "4"),
+					resource.TestCheckResourceAttr("data.dx_virtual_network_free_space.test", "free_cidrs.0", "10.10.2.32/27"),
+					resource.TestCheckResourceAttr("data.dx_virtual_network_free_space.test", "free_cidrs.3", "10.10.3.0/24"),
+					resource.TestCheckResourceAttr("data.dx_virtual_network_free_space.test", "largest_available_prefix_length", "24"),
+					resource.TestCheckResourceAttr("data.dx_virtual_network_free_space.test", "available_blocks.26", "7"),
+				),
+			},
+			{
+				// Changing the prefix lengths keeps the unchanged blocks, allocating the added subnets
+				// while the removed blocks are still reserved
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidrs" "test" {
+  virtual_network_id = %q
+  prefix_lengths = {
+    apps = 24
+    func = 26
+  }
+}
+`, vnetID),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("dx_available_subnet_cidrs.test", plancheck.ResourceActionUpdate),
+						plancheck.ExpectKnownValue("dx_available_subnet_cidrs.test", tfjsonpath.New("cidr_blocks").AtMapKey("apps"), knownvalue.StringExact("10.10.1.0/24")),
+						plancheck.ExpectUnknownValue("dx_available_subnet_cidrs.test", tfjsonpath.New("cidr_blocks").AtMapKey("func")),
+					},
+				},
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "id", vnetID+"|apps=10.10.1.0/24,func=10.10.2.64/26"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.%", "2"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.apps", "10.10.1.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.func", "10.10.2.64/26"),
+				),
+			},
+			{
+				// The block of the removed subnet was released and is handed out again
+				Config: fmt.Sprintf(`
+resource "dx_available_subnet_cidrs" "test" {
+  virtual_network_id = %q
+  prefix_lengths = {
+    apps = 24
+    func = 26
+    pep  = 27
+  }
+}
+`, vnetID),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.apps", "10.10.1.0/24"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.func", "10.10.2.64/26"),
+					resource.TestCheckResourceAttr("dx_available_subnet_cidrs.test", "cidr_blocks.pep", "10.10.2.0/27"),
+				),
+			},
+			{
+				ResourceName:      "dx_available_subnet_cidrs.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}